@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const mediaNamespace = "http://search.yahoo.com/mrss/"
+
+// atomText is an Atom text construct. Text and HTML content arrive as
+// character data; XHTML content is kept as re-serialized markup.
+type atomText struct {
+	Type string
+	Body string
+}
+
+func (t *atomText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" {
+			t.Type = strings.ToLower(attr.Value)
+		}
+	}
+	if t.Type == "xhtml" {
+		var raw RawElement
+		if err := raw.UnmarshalXML(d, start); err != nil {
+			return err
+		}
+		t.Body = strings.TrimSpace(raw.Inner)
+		return nil
+	}
+	var body struct {
+		Value string `xml:",chardata"`
+	}
+	if err := d.DecodeElement(&body, &start); err != nil {
+		return err
+	}
+	t.Body = strings.TrimSpace(body.Value)
+	return nil
+}
+
+// html returns the text as HTML, escaping plain text.
+func (t atomText) html() string {
+	if t.Type == "" || t.Type == "text" {
+		return html.EscapeString(t.Body)
+	}
+	return t.Body
+}
+
+// plain returns the text with any markup removed.
+func (t atomText) plain() string {
+	if t.Type == "" || t.Type == "text" {
+		return t.Body
+	}
+	return strings.TrimSpace(html.UnescapeString(htmlTagPattern.ReplaceAllString(t.Body, "")))
+}
+
+type atomPerson struct {
+	Name string `xml:"http://www.w3.org/2005/Atom name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomLinkElement struct {
+	AtomLink
+	Length string `xml:"length,attr"`
+}
+
+type atomEntry struct {
+	Title      atomText          `xml:"http://www.w3.org/2005/Atom title"`
+	Links      []atomLinkElement `xml:"http://www.w3.org/2005/Atom link"`
+	ID         string            `xml:"http://www.w3.org/2005/Atom id"`
+	Published  string            `xml:"http://www.w3.org/2005/Atom published"`
+	Updated    string            `xml:"http://www.w3.org/2005/Atom updated"`
+	Authors    []atomPerson      `xml:"http://www.w3.org/2005/Atom author"`
+	Summary    atomText          `xml:"http://www.w3.org/2005/Atom summary"`
+	Content    atomText          `xml:"http://www.w3.org/2005/Atom content"`
+	Categories []atomCategory    `xml:"http://www.w3.org/2005/Atom category"`
+	Extra      []RawElement      `xml:",any"`
+}
+
+// atomFeedState collects the feed-level Atom elements entries fall back on.
+type atomFeedState struct {
+	id      string
+	authors []string
+}
+
+// decodeAtomFeedElement handles a direct child of an Atom <feed>.
+func decodeAtomFeedElement(decoder *xml.Decoder, start xml.StartElement, channel *Channel, state *atomFeedState) error {
+	if start.Name.Space != atomNamespace {
+		return decoder.Skip()
+	}
+	switch start.Name.Local {
+	case "title":
+		var title atomText
+		if err := decoder.DecodeElement(&title, &start); err != nil {
+			return err
+		}
+		channel.Title = title.plain()
+	case "subtitle":
+		var subtitle atomText
+		if err := decoder.DecodeElement(&subtitle, &start); err != nil {
+			return err
+		}
+		channel.Description = subtitle.plain()
+	case "link":
+		var link AtomLink
+		if err := decoder.DecodeElement(&link, &start); err != nil {
+			return err
+		}
+		if link.Rel == "" || link.Rel == "alternate" {
+			if channel.Link == "" {
+				channel.Link = link.Href
+			}
+			return nil
+		}
+		channel.AtomLinks = append(channel.AtomLinks, link)
+	case "id":
+		return decoder.DecodeElement(&state.id, &start)
+	case "generator":
+		return decoder.DecodeElement(&channel.Generator, &start)
+	case "author":
+		var author atomPerson
+		if err := decoder.DecodeElement(&author, &start); err != nil {
+			return err
+		}
+		if name := strings.TrimSpace(author.Name); name != "" {
+			state.authors = append(state.authors, name)
+		}
+	case "logo", "icon":
+		var imageURL string
+		if err := decoder.DecodeElement(&imageURL, &start); err != nil {
+			return err
+		}
+		// Prefer the logo; the icon is usually a favicon.
+		if channel.Image == nil || start.Name.Local == "logo" {
+			channel.Image = &Image{URL: strings.TrimSpace(imageURL), Title: channel.Title, Link: channel.Link}
+		}
+	default:
+		return decoder.Skip()
+	}
+	return nil
+}
+
+// item maps an Atom entry onto the RSS item model.
+func (e atomEntry) item() Item {
+	item := Item{
+		Title:       e.Title.plain(),
+		Description: e.Summary.html(),
+		Content:     e.Content.html(),
+		GUID:        GUID{Value: strings.TrimSpace(e.ID), IsPermaLink: "false"},
+	}
+	if item.GUID.Value == "" {
+		item.GUID = GUID{}
+	}
+
+	for _, link := range e.Links {
+		switch link.Rel {
+		case "", "alternate":
+			if item.Link == "" || strings.Contains(link.Type, "html") {
+				item.Link = link.Href
+			}
+		case "enclosure":
+			attrs := []xml.Attr{{Name: xml.Name{Local: "url"}, Value: link.Href}}
+			if link.Length != "" {
+				attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "length"}, Value: link.Length})
+			}
+			if link.Type != "" {
+				attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "type"}, Value: link.Type})
+			}
+			item.Extra = append(item.Extra, RawElement{Name: xml.Name{Local: "enclosure"}, Attrs: attrs})
+		}
+	}
+
+	date := e.Published
+	if strings.TrimSpace(date) == "" {
+		date = e.Updated
+	}
+	if t, err := parseRSSDate(date); err == nil {
+		item.PubDate = t.Format(time.RFC1123Z)
+	} else {
+		item.PubDate = strings.TrimSpace(date)
+	}
+
+	for _, author := range e.Authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			item.Creators = append(item.Creators, name)
+		}
+	}
+	for _, category := range e.Categories {
+		if category.Label != "" {
+			item.Categories = append(item.Categories, category.Label)
+		} else if category.Term != "" {
+			item.Categories = append(item.Categories, category.Term)
+		}
+	}
+
+	// Unmapped Atom elements would be meaningless in RSS output; extension
+	// elements such as media:group are kept.
+	for _, extra := range e.Extra {
+		if extra.Name.Space != atomNamespace {
+			item.Extra = append(item.Extra, extra)
+		}
+	}
+	return item
+}
+
+// applyAtomFallbacks gives entries without authors the feed's authors, as
+// the Atom spec says they inherit them, then applies the quirks of the site
+// the feed comes from.
+func applyAtomFallbacks(channel *Channel, state atomFeedState) {
+	for i := range channel.Items {
+		if len(channel.Items[i].authors()) == 0 {
+			channel.Items[i].Creators = append([]string(nil), state.authors...)
+		}
+	}
+
+	switch feedFlavor(channel, state.id) {
+	case "youtube":
+		for i := range channel.Items {
+			youtubeQuirks(&channel.Items[i])
+		}
+	case "reddit":
+		for i := range channel.Items {
+			redditQuirks(&channel.Items[i])
+		}
+	case "github":
+		for i := range channel.Items {
+			githubQuirks(&channel.Items[i])
+		}
+	}
+}
+
+// feedFlavor recognizes feeds from sites whose Atom needs special handling,
+// by generator or by where the feed's links point.
+func feedFlavor(channel *Channel, id string) string {
+	candidates := []string{channel.Generator, channel.Link, id}
+	for _, link := range channel.AtomLinks {
+		candidates = append(candidates, link.Href)
+	}
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+		switch {
+		case strings.Contains(candidate, "youtube.com/"):
+			return "youtube"
+		case strings.Contains(candidate, "reddit.com/"):
+			return "reddit"
+		case strings.Contains(candidate, "github.com"):
+			return "github"
+		}
+	}
+	return ""
+}
+
+// youtubeQuirks takes the description and thumbnail from media:group, where
+// YouTube puts them instead of in the entry itself.
+func youtubeQuirks(item *Item) {
+	for _, extra := range item.Extra {
+		if extra.Name.Space != mediaNamespace || extra.Name.Local != "group" {
+			continue
+		}
+		if item.Description == "" {
+			if description, _ := extra.child(mediaNamespace, "description"); description != nil {
+				item.Description = html.EscapeString(strings.TrimSpace(description.text))
+				item.Description = strings.ReplaceAll(item.Description, "\n", "<br>\n")
+			}
+		}
+		if thumbnail, _ := extra.child(mediaNamespace, "thumbnail"); thumbnail != nil && thumbnail.attrs["url"] != "" {
+			item.Extra = append(item.Extra, RawElement{
+				Name:  xml.Name{Space: mediaNamespace, Local: "thumbnail"},
+				Attrs: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: thumbnail.attrs["url"]}},
+			})
+		}
+		return
+	}
+}
+
+// redditQuirks turns "/u/name" authors into plain user names, so author
+// lists don't need Reddit's prefix.
+func redditQuirks(item *Item) {
+	for i, creator := range item.Creators {
+		creator = strings.TrimPrefix(creator, "/")
+		item.Creators[i] = strings.TrimPrefix(creator, "u/")
+	}
+}
+
+// githubQuirks makes release entries stand on their own: their titles are
+// often a bare tag such as "v1.2.3", so the repository name is prepended and
+// added as a category, and the owner stands in for a missing author.
+func githubQuirks(item *Item) {
+	u, err := url.Parse(item.Link)
+	if err != nil || !strings.HasSuffix(u.Host, "github.com") {
+		return
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return
+	}
+	owner, repo := parts[0], parts[1]
+	if !strings.Contains(strings.ToLower(item.Title), strings.ToLower(repo)) {
+		item.Title = strings.TrimSpace(repo + " " + item.Title)
+	}
+	item.Categories = append(item.Categories, owner+"/"+repo)
+	if len(item.authors()) == 0 {
+		item.Creators = []string{owner}
+	}
+}
+
+type rawChild struct {
+	attrs map[string]string
+	text  string
+}
+
+// child finds the first descendant element of e named space:local.
+func (e RawElement) child(space, local string) (*rawChild, error) {
+	decoder := xml.NewDecoder(strings.NewReader(e.Inner))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != space || start.Name.Local != local {
+			continue
+		}
+		child := &rawChild{attrs: make(map[string]string)}
+		for _, attr := range start.Attr {
+			child.attrs[attr.Name.Local] = attr.Value
+		}
+		var text bytes.Buffer
+		for depth := 0; depth >= 0; {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				depth++
+			case xml.EndElement:
+				depth--
+			case xml.CharData:
+				text.Write(t)
+			}
+		}
+		child.text = text.String()
+		return child, nil
+	}
+}
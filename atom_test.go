@@ -0,0 +1,149 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAtomEntryItem(t *testing.T) {
+	doc := `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+<title type="html">Blog &amp;lt;3</title>
+<subtitle>All posts</subtitle>
+<link href="https://example.com/"/>
+<link rel="self" href="https://example.com/atom.xml"/>
+<logo>https://example.com/logo.png</logo>
+<entry>
+  <title type="html">&lt;b&gt;Bold&lt;/b&gt; move</title>
+  <id>urn:1</id>
+  <link rel="alternate" type="application/json" href="https://example.com/1.json"/>
+  <link rel="alternate" type="text/html" href="https://example.com/1"/>
+  <link rel="enclosure" type="audio/mpeg" length="42" href="https://example.com/1.mp3"/>
+  <updated>2026-10-12T12:00:00+02:00</updated>
+  <author><name>Alice</name></author>
+  <summary>1 &lt; 2</summary>
+  <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Body</p></div></content>
+  <category term="go" label="Go"/>
+  <category term="data"/>
+  <media:rating>nonadult</media:rating>
+</entry>
+</feed>`
+	channel := decodeTestFeed(t, doc, decodeOptions{})
+	if channel.Title != "Blog <3" || channel.Description != "All posts" || channel.Link != "https://example.com/" {
+		t.Errorf("channel = %q, %q, %q", channel.Title, channel.Description, channel.Link)
+	}
+	if channel.selfLink() != "https://example.com/atom.xml" || channel.Image == nil || channel.Image.URL != "https://example.com/logo.png" {
+		t.Errorf("self link %q, image %+v", channel.selfLink(), channel.Image)
+	}
+	if len(channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(channel.Items))
+	}
+	item := channel.Items[0]
+	checks := []struct {
+		field, got, want string
+	}{
+		{"title", item.Title, "Bold move"},
+		{"link", item.Link, "https://example.com/1"},
+		{"guid", item.GUID.Value, "urn:1"},
+		{"pubDate", item.PubDate, "Mon, 12 Oct 2026 12:00:00 +0200"},
+		{"description", item.Description, "1 &lt; 2"},
+		{"categories", strings.Join(item.Categories, ","), "Go,data"},
+		{"creators", strings.Join(item.Creators, ","), "Alice"},
+		{"enclosure types", strings.Join(item.enclosureTypes(), ","), "audio/mpeg"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+	if !strings.Contains(item.Content, "<p") || !strings.Contains(item.Content, "Body") {
+		t.Errorf("content = %q, want the XHTML markup", item.Content)
+	}
+	var extras []string
+	for _, extra := range item.Extra {
+		extras = append(extras, extra.Name.Local)
+	}
+	if want := []string{"enclosure", "rating"}; !reflect.DeepEqual(extras, want) {
+		t.Errorf("extras = %q, want %q", extras, want)
+	}
+}
+
+func TestAtomFeedQuirks(t *testing.T) {
+	tests := []struct {
+		name, doc  string
+		title      string
+		creators   []string
+		categories []string
+		check      func(t *testing.T, item Item)
+	}{
+		{
+			name: "youtube",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+<link rel="alternate" href="https://www.youtube.com/channel/UC123"/>
+<author><name>Channel</name></author>
+<entry><title>Video</title><link href="https://www.youtube.com/watch?v=1"/>
+<media:group><media:description>Line 1
+Line &amp; 2</media:description><media:thumbnail url="https://i.ytimg.com/1.jpg"/></media:group></entry>
+</feed>`,
+			title:    "Video",
+			creators: []string{"Channel"},
+			check: func(t *testing.T, item Item) {
+				if item.Description != "Line 1<br>\nLine &amp; 2" {
+					t.Errorf("description = %q", item.Description)
+				}
+				if item.image() != "https://i.ytimg.com/1.jpg" {
+					t.Errorf("image = %q", item.image())
+				}
+			},
+		},
+		{
+			name: "reddit",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom">
+<link rel="alternate" href="https://www.reddit.com/r/golang/"/>
+<entry><title>Question</title><author><name>/u/gopher</name></author><link href="https://www.reddit.com/r/golang/comments/1/"/></entry>
+</feed>`,
+			title:    "Question",
+			creators: []string{"gopher"},
+		},
+		{
+			name: "github releases",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom">
+<id>tag:github.com,2008:https://github.com/acme/widget/releases</id>
+<entry><title>v1.2.3</title><link rel="alternate" type="text/html" href="https://github.com/acme/widget/releases/tag/v1.2.3"/></entry>
+<entry><title>Widget 2.0</title><author><name>bob</name></author><link href="https://github.com/acme/widget/releases/tag/v2.0.0"/></entry>
+</feed>`,
+			title:      "widget v1.2.3",
+			creators:   []string{"acme"},
+			categories: []string{"acme/widget"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := decodeTestFeed(t, tt.doc, decodeOptions{}).Items
+			if len(items) == 0 {
+				t.Fatal("no items")
+			}
+			item := items[0]
+			if item.Title != tt.title {
+				t.Errorf("title = %q, want %q", item.Title, tt.title)
+			}
+			if !reflect.DeepEqual(item.Creators, tt.creators) {
+				t.Errorf("creators = %q, want %q", item.Creators, tt.creators)
+			}
+			if !reflect.DeepEqual(item.Categories, tt.categories) {
+				t.Errorf("categories = %q, want %q", item.Categories, tt.categories)
+			}
+			if tt.check != nil {
+				tt.check(t, item)
+			}
+		})
+	}
+}
+
+func TestGithubQuirksKeepsNamedTitles(t *testing.T) {
+	item := Item{Title: "Widget 2.0", Creators: []string{"bob"}, Link: "https://github.com/acme/widget/releases/tag/v2.0.0"}
+	githubQuirks(&item)
+	if item.Title != "Widget 2.0" || !reflect.DeepEqual(item.Creators, []string{"bob"}) {
+		t.Errorf("item = %+v", item)
+	}
+}
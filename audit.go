@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type auditRecord struct {
+	Time    time.Time `json:"time"`
+	Feed    string    `json:"feed"`
+	Rule    string    `json:"rule"`
+	GUID    string    `json:"guid,omitempty"`
+	Link    string    `json:"link,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Authors []string  `json:"authors,omitempty"`
+	PubDate string    `json:"pub_date,omitempty"`
+}
+
+// appendAuditLog appends one JSON line to path for every item a filter
+// drops, so it's possible to tell afterwards why a post didn't make it into
+// the output. The file is never truncated; each run adds to it.
+func appendAuditLog(path, feed string, items []Item, filters []itemFilter, now time.Time) (int, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	written := 0
+	for _, item := range items {
+		rule := firstRejectingFilter(item, filters)
+		if rule == "" {
+			continue
+		}
+		record := auditRecord{
+			Time:    now.UTC(),
+			Feed:    feed,
+			Rule:    rule,
+			GUID:    item.GUID.Value,
+			Link:    item.Link,
+			Title:   item.Title,
+			Authors: item.authors(),
+			PubDate: item.PubDate,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return written, fmt.Errorf("failed to write audit log: %w", err)
+		}
+		written++
+	}
+	return written, file.Close()
+}
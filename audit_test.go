@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	filters := []itemFilter{
+		{name: "authors", keep: func(item Item) bool { return len(item.authors()) > 0 }},
+		{name: "title", keep: func(item Item) bool { return item.Title != "" }},
+	}
+	items := []Item{
+		{Title: "Kept", Creators: []string{"Alice"}},
+		{Title: "No author <b>", Link: "https://example.com/1", PubDate: "Mon, 12 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "id-2"}, Creators: []string{"Bob"}},
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	// Runs append to the same file.
+	for run := 0; run < 2; run++ {
+		written, err := appendAuditLog(path, "https://example.com/feed", items, filters, now)
+		if err != nil || written != 2 {
+			t.Fatalf("run %d: appendAuditLog = %d, %v; want 2 records", run, written, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), data)
+	}
+	if !strings.Contains(lines[0], `"title":"No author <b>"`) {
+		t.Errorf("line = %s, want HTML left unescaped", lines[0])
+	}
+	var records []auditRecord
+	for _, line := range lines[:2] {
+		var record auditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	want := []auditRecord{
+		{Time: now.UTC(), Feed: "https://example.com/feed", Rule: "authors", Link: "https://example.com/1",
+			Title: "No author <b>", PubDate: "Mon, 12 Oct 2026 10:00:00 +0000"},
+		{Time: now.UTC(), Feed: "https://example.com/feed", Rule: "title", GUID: "id-2", Authors: []string{"Bob"}},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %+v, want %+v", records, want)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadAllowedAuthors merges the allowed-author lists from ALLOWED_AUTHOR_LIST
+// (or the file in ALLOWED_AUTHOR_LIST_FILE), --authors-file and --authors-url.
+// All three use the same format: one name per line, blank lines and lines
+// starting with # ignored.
+func (f *filterFlags) loadAllowedAuthors() (map[string]bool, error) {
+	env, err := getenvSecret("ALLOWED_AUTHOR_LIST")
+	if err != nil {
+		return nil, err
+	}
+
+	var lists []string
+	if env != "" {
+		lists = append(lists, env)
+	} else if f.authorsFile == "" && f.authorsURL == "" {
+		return nil, errors.New("--authors flag requires ALLOWED_AUTHOR_LIST (or ALLOWED_AUTHOR_LIST_FILE) environment variable, --authors-file or --authors-url")
+	}
+	if f.authorsFile != "" {
+		data, err := os.ReadFile(f.authorsFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading authors file: %w", err)
+		}
+		lists = append(lists, string(data))
+	}
+	if f.authorsURL != "" {
+		list, err := fetchAuthorList(f.authorsURL)
+		if err != nil {
+			return nil, fmt.Errorf("fetching authors list: %w", err)
+		}
+		lists = append(lists, list)
+	}
+
+	allowed := loadAllowedAuthorsFromEnv(strings.Join(lists, "\n"))
+	if len(allowed) == 0 {
+		return nil, errors.New("the allowed author list is empty")
+	}
+	return allowed, nil
+}
+
+// fetchAuthorList downloads an author list, keeping a copy in the user cache
+// directory. The cached copy is revalidated with its ETag, and used as-is
+// when the URL can't be reached.
+func fetchAuthorList(listURL string) (string, error) {
+	cachePath := authorListCachePath(listURL)
+	cached, cacheErr := os.ReadFile(cachePath)
+	etag, _ := os.ReadFile(cachePath + ".etag")
+
+	header := make(http.Header)
+	if cacheErr == nil && len(etag) > 0 {
+		header.Set("If-None-Match", string(etag))
+	}
+
+	resp, err := httpGetWithHeader(listURL, header)
+	if err != nil {
+		if cacheErr == nil {
+			warnf("fetching %s failed, using cached copy: %v", listURL, err)
+			return string(cached), nil
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cacheErr == nil:
+		debugf("Author list %s not modified, using cached copy", listURL)
+		return string(cached), nil
+	case resp.StatusCode != http.StatusOK:
+		if cacheErr == nil {
+			warnf("fetching %s returned status %d, using cached copy", listURL, resp.StatusCode)
+			return string(cached), nil
+		}
+		return "", fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	// A cut-off list would silently drop authors, now and from the cache.
+	if len(body) > maxPageBytes {
+		return "", fmt.Errorf("author list is larger than %d bytes", maxPageBytes)
+	}
+	if cachePath != "" {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+			os.WriteFile(cachePath, body, 0644)
+			os.WriteFile(cachePath+".etag", []byte(resp.Header.Get("ETag")), 0644)
+		}
+	}
+	return string(body), nil
+}
+
+func authorListCachePath(listURL string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(listURL))
+	return filepath.Join(dir, "filtered-data-rss", fmt.Sprintf("authors-%x.txt", hash[:8]))
+}
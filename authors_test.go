@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLoadAllowedAuthors(t *testing.T) {
+	useTempCacheDir(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# from the URL\nCarol\n"))
+	}))
+	defer server.Close()
+	file := filepath.Join(t.TempDir(), "authors.txt")
+	if err := os.WriteFile(file, []byte("Bob\n\n# comment\n  Alice  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		env   string
+		flags filterFlags
+		want  []string
+	}{
+		{"env", "Alice\nBob", filterFlags{authors: true}, []string{"Alice", "Bob"}},
+		{"file", "", filterFlags{authorsFile: file}, []string{"Alice", "Bob"}},
+		{"url", "", filterFlags{authorsURL: server.URL}, []string{"Carol"}},
+		{"all merged", "Dave", filterFlags{authorsFile: file, authorsURL: server.URL}, []string{"Alice", "Bob", "Carol", "Dave"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_AUTHOR_LIST", tt.env)
+			allowed, err := tt.flags.loadAllowedAuthors()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for name := range allowed {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowed = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("ALLOWED_AUTHOR_LIST", "")
+	for _, flags := range []filterFlags{{authors: true}, {authorsFile: filepath.Join(t.TempDir(), "missing")}} {
+		if _, err := flags.loadAllowedAuthors(); err == nil {
+			t.Errorf("loadAllowedAuthors with %+v succeeded", flags)
+		}
+	}
+}
+
+func TestFetchAuthorListCache(t *testing.T) {
+	useTempCacheDir(t)
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("Alice\n"))
+	}))
+	defer server.Close()
+
+	steps := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{"first fetch", 0, "Alice\n"},
+		{"not modified", 0, "Alice\n"},
+		{"server error", http.StatusInternalServerError, "Alice\n"},
+	}
+	for _, step := range steps {
+		status = step.status
+		got, err := fetchAuthorList(server.URL)
+		if err != nil || got != step.want {
+			t.Errorf("%s: fetchAuthorList = %q, %v; want %q", step.name, got, err, step.want)
+		}
+	}
+
+	server.Close()
+	if got, err := fetchAuthorList(server.URL); err != nil || got != "Alice\n" {
+		t.Errorf("unreachable: fetchAuthorList = %q, %v; want the cached copy", got, err)
+	}
+	if _, err := fetchAuthorList(server.URL + "/uncached"); err == nil {
+		t.Error("fetchAuthorList of an unreachable, uncached URL succeeded")
+	}
+}
+
+func TestFetchAuthorListTooLarge(t *testing.T) {
+	useTempCacheDir(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("Alice\n"), maxPageBytes/6+1))
+	}))
+	defer server.Close()
+
+	if _, err := fetchAuthorList(server.URL); err == nil {
+		t.Error("fetchAuthorList of an oversized list succeeded")
+	}
+	if _, err := os.Stat(authorListCachePath(server.URL)); !os.IsNotExist(err) {
+		t.Errorf("oversized list was cached: %v", err)
+	}
+}
+
+// useTempCacheDir points os.UserCacheDir at a temporary directory.
+func useTempCacheDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", dir)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// bigQueryAPI is a variable so tests can point it at a fake server.
+var bigQueryAPI = "https://bigquery.googleapis.com"
+
+type bigQueryField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Mode        string `json:"mode"`
+	Description string `json:"description"`
+}
+
+// bigQuerySchema describes the rows written by outputNDJSON, in the JSON
+// format `bq load --schema` and the BigQuery API take.
+var bigQuerySchema = []bigQueryField{
+	{"guid", "STRING", "NULLABLE", "Item GUID"},
+	{"link", "STRING", "NULLABLE", "Item link"},
+	{"title", "STRING", "NULLABLE", "Item title"},
+	{"published_at", "TIMESTAMP", "NULLABLE", "Publication date; null when the feed's date can't be parsed"},
+	{"authors", "STRING", "REPEATED", "Item authors"},
+	{"categories", "STRING", "REPEATED", "Item categories"},
+	{"description", "STRING", "NULLABLE", "Item description (HTML)"},
+	{"content", "STRING", "NULLABLE", "Item content (HTML)"},
+	{"feed", "STRING", "NULLABLE", "Feed URL or directory the item was read from"},
+	{"fetched_at", "TIMESTAMP", "REQUIRED", "Start of the run that emitted the item"},
+	{"image", "STRING", "NULLABLE", "Thumbnail URL, from the feed or --enrich opengraph"},
+}
+
+type bigQueryRow struct {
+	GUID        string   `json:"guid,omitempty"`
+	Link        string   `json:"link,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	PublishedAt string   `json:"published_at,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	Feed        string   `json:"feed,omitempty"`
+	FetchedAt   string   `json:"fetched_at"`
+	Image       string   `json:"image,omitempty"`
+}
+
+// outputNDJSON writes one JSON object per item and line, matching
+// bigQuerySchema, so a day's snapshot can be appended to a table as is.
+func outputNDJSON(w io.Writer, items []Item, feed string, now time.Time) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	fetchedAt := now.UTC().Format(time.RFC3339)
+	for _, item := range cleanItems(items) {
+		row := bigQueryRow{
+			GUID:        item.GUID.Value,
+			Link:        item.Link,
+			Title:       item.Title,
+			Authors:     item.authors(),
+			Categories:  item.Categories,
+			Description: item.Description,
+			Content:     item.Content,
+			Feed:        feed,
+			FetchedAt:   fetchedAt,
+			Image:       item.image(),
+		}
+		if t, err := parseRSSDate(item.PubDate); err == nil {
+			row.PublishedAt = t.UTC().Format(time.RFC3339)
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBigQuerySchema(path string) error {
+	data, err := json.MarshalIndent(bigQuerySchema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// parseBigQueryTable splits "project.dataset.table" (or the bq tool's
+// "project:dataset.table") into its parts.
+func parseBigQueryTable(spec string) (project, dataset, table string, err error) {
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		project, spec = spec[:i], spec[i+1:]
+		parts := strings.Split(spec, ".")
+		if len(parts) == 2 && project != "" && parts[0] != "" && parts[1] != "" {
+			return project, parts[0], parts[1], nil
+		}
+	} else if parts := strings.Split(spec, "."); len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "" {
+		return parts[0], parts[1], parts[2], nil
+	}
+	return "", "", "", fmt.Errorf("invalid BigQuery table %q (want project.dataset.table)", spec)
+}
+
+// bigQueryPollInterval is how often a running load job is checked on.
+var bigQueryPollInterval = 2 * time.Second
+
+type bigQueryJob struct {
+	JobReference struct {
+		ProjectID string `json:"projectId"`
+		JobID     string `json:"jobId"`
+		Location  string `json:"location"`
+	} `json:"jobReference"`
+	Status struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Message string `json:"message"`
+		} `json:"errorResult"`
+	} `json:"status"`
+}
+
+// loadBigQuery appends items to a BigQuery table with a load job, creating
+// the table with bigQuerySchema if it doesn't exist, and waits up to timeout
+// for the job to finish. It authenticates like gcp-secret: references do.
+func loadBigQuery(tableSpec string, items []Item, feed string, now time.Time, timeout time.Duration) error {
+	project, dataset, table, err := parseBigQueryTable(tableSpec)
+	if err != nil {
+		return err
+	}
+	token, err := gcpAccessToken()
+	if err != nil {
+		return fmt.Errorf("getting GCP access token: %w", err)
+	}
+
+	var data bytes.Buffer
+	if err := outputNDJSON(&data, items, feed, now); err != nil {
+		return err
+	}
+	config := map[string]interface{}{
+		"configuration": map[string]interface{}{
+			"load": map[string]interface{}{
+				"destinationTable":  map[string]string{"projectId": project, "datasetId": dataset, "tableId": table},
+				"schema":            map[string]interface{}{"fields": bigQuerySchema},
+				"sourceFormat":      "NEWLINE_DELIMITED_JSON",
+				"writeDisposition":  "WRITE_APPEND",
+				"createDisposition": "CREATE_IF_NEEDED",
+				// Columns added in later versions are added to older tables.
+				"schemaUpdateOptions": []string{"ALLOW_FIELD_ADDITION"},
+			},
+		},
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"application/json; charset=UTF-8", configJSON},
+		{"application/octet-stream", data.Bytes()},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return err
+		}
+		writer.Write(part.data)
+	}
+	parts.Close()
+
+	endpoint := bigQueryAPI + "/upload/bigquery/v2/projects/" + url.PathEscape(project) + "/jobs?uploadType=multipart"
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "multipart/related; boundary="+parts.Boundary())
+	var job bigQueryJob
+	if err := serviceRequest(req, &job); err != nil {
+		return fmt.Errorf("starting load job: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for job.Status.State != "DONE" {
+		if time.Now().Add(bigQueryPollInterval).After(deadline) {
+			return fmt.Errorf("load job %s still %s after %s", job.JobReference.JobID, strings.ToLower(job.Status.State), timeout)
+		}
+		time.Sleep(bigQueryPollInterval)
+		endpoint := bigQueryAPI + "/bigquery/v2/projects/" + url.PathEscape(job.JobReference.ProjectID) +
+			"/jobs/" + url.PathEscape(job.JobReference.JobID) + "?location=" + url.QueryEscape(job.JobReference.Location)
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if err := serviceRequest(req, &job); err != nil {
+			return fmt.Errorf("checking load job %s: %w", job.JobReference.JobID, err)
+		}
+	}
+	if job.Status.ErrorResult != nil {
+		return fmt.Errorf("load job %s failed: %s", job.JobReference.JobID, job.Status.ErrorResult.Message)
+	}
+	return nil
+}
+
+// exportBigQuery writes the schema file and runs the load job, when asked.
+func exportBigQuery(schemaPath, tableSpec, feed string, items []Item, now time.Time, timeout time.Duration) {
+	if schemaPath != "" {
+		if err := writeBigQuerySchema(schemaPath); err != nil {
+			fatalf("Error writing BigQuery schema: %v", err)
+		}
+	}
+	if tableSpec == "" {
+		return
+	}
+	if err := loadBigQuery(tableSpec, items, feed, now, timeout); err != nil {
+		fatalf("Error loading into BigQuery: %v", err)
+	}
+	infof("Loaded %d items into %s", len(items), tableSpec)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBigQueryTable(t *testing.T) {
+	tests := []struct {
+		in                      string
+		project, dataset, table string
+		wantErr                 bool
+	}{
+		{in: "my-project.feeds.items", project: "my-project", dataset: "feeds", table: "items"},
+		{in: "my-project:feeds.items", project: "my-project", dataset: "feeds", table: "items"},
+		{in: "example.com:my-project:feeds.items", project: "example.com:my-project", dataset: "feeds", table: "items"},
+		{in: "feeds.items", wantErr: true},
+		{in: "a..c", wantErr: true},
+	}
+	for _, tt := range tests {
+		project, dataset, table, err := parseBigQueryTable(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBigQueryTable(%q): err = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if project != tt.project || dataset != tt.dataset || table != tt.table {
+			t.Errorf("parseBigQueryTable(%q) = %q, %q, %q", tt.in, project, dataset, table)
+		}
+	}
+}
+
+// fakeBigQuery answers load jobs with states, one per request: the insert
+// gets the first, every poll the next, and the last repeats.
+func fakeBigQuery(t *testing.T, states ...string) *httptest.Server {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		state := states[len(states)-1]
+		if requests < len(states) {
+			state = states[requests]
+		}
+		requests++
+		var job bigQueryJob
+		job.JobReference.ProjectID, job.JobReference.JobID, job.JobReference.Location = "p", "job-1", "EU"
+		job.Status.State = state
+		if state == "FAILED" {
+			job.Status.State = "DONE"
+			job.Status.ErrorResult = &struct {
+				Message string `json:"message"`
+			}{Message: "no such dataset"}
+		}
+		json.NewEncoder(w).Encode(job)
+	}))
+	t.Cleanup(server.Close)
+
+	api, interval := bigQueryAPI, bigQueryPollInterval
+	bigQueryAPI, bigQueryPollInterval = server.URL, time.Millisecond
+	t.Cleanup(func() { bigQueryAPI, bigQueryPollInterval = api, interval })
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "test-token")
+	return server
+}
+
+func TestLoadBigQuery(t *testing.T) {
+	items := []Item{{Title: "A", Link: "https://example.com/a"}}
+	now := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+
+	t.Run("done", func(t *testing.T) {
+		fakeBigQuery(t, "PENDING", "RUNNING", "DONE")
+		if err := loadBigQuery("p.feeds.items", items, "feed", now, time.Minute); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("failed", func(t *testing.T) {
+		fakeBigQuery(t, "RUNNING", "FAILED")
+		err := loadBigQuery("p.feeds.items", items, "feed", now, time.Minute)
+		if err == nil || !strings.Contains(err.Error(), "no such dataset") {
+			t.Errorf("err = %v, want the job's error", err)
+		}
+	})
+	t.Run("stuck", func(t *testing.T) {
+		fakeBigQuery(t, "RUNNING")
+		start := time.Now()
+		err := loadBigQuery("p.feeds.items", items, "feed", now, 50*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "still running") {
+			t.Errorf("err = %v, want a timeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("gave up after %s", elapsed)
+		}
+	})
+}
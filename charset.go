@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// windows1252Specials maps the bytes 0x80-0x9F, where Windows-1252 differs
+// from ISO-8859-1, to the characters they stand for.
+var windows1252Specials = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// normalizeFeedStart strips byte order marks and anything before the first
+// '<', and transcodes UTF-16 documents to UTF-8, so feeds with a sloppy
+// prologue decode the same as clean ones.
+func normalizeFeedStart(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return normalizeFeedStart(bytes.NewReader(decodeUTF16(data)))
+	}
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return br, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == '<' {
+			br.UnreadByte()
+			return br, nil
+		}
+	}
+}
+
+func decodeUTF16(data []byte) []byte {
+	bigEndian := data[0] == 0xFE
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// charsetReader lets the XML decoder read feeds that declare a legacy
+// single-byte encoding. UTF-16 input has already been transcoded by
+// normalizeFeedStart, so its declaration is accepted as-is.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "utf-8", "utf8", "us-ascii", "ascii", "utf-16", "utf-16le", "utf-16be":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "l1":
+		return transcodeSingleByte(input, false)
+	case "windows-1252", "cp1252", "x-cp1252":
+		return transcodeSingleByte(input, true)
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+func transcodeSingleByte(input io.Reader, windows1252 bool) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for _, b := range data {
+		r := rune(b)
+		if windows1252 && b >= 0x80 && b <= 0x9F {
+			r = windows1252Specials[b-0x80]
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return bytes.NewReader(out), nil
+}
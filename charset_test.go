@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, bigEndian bool) []byte {
+	out := []byte{0xFF, 0xFE}
+	if bigEndian {
+		out = []byte{0xFE, 0xFF}
+	}
+	for _, unit := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			out = append(out, byte(unit>>8), byte(unit))
+		} else {
+			out = append(out, byte(unit), byte(unit>>8))
+		}
+	}
+	return out
+}
+
+func TestDecodeFeedEncodings(t *testing.T) {
+	const body = `<rss><channel><item><title>Café “quoted”</title></item></channel></rss>`
+	tests := []struct {
+		name, doc string
+	}{
+		{"UTF-8", `<?xml version="1.0" encoding="UTF-8"?>` + body},
+		{"BOM", "\xEF\xBB\xBF" + `<?xml version="1.0"?>` + body},
+		{"junk before the declaration", "\n\n  \x00garbage" + `<?xml version="1.0"?>` + body},
+		{"UTF-16LE", string(utf16Bytes(`<?xml version="1.0" encoding="UTF-16"?>`+body, false))},
+		{"UTF-16BE", string(utf16Bytes(`<?xml version="1.0" encoding="utf-16"?>`+body, true))},
+		{"Windows-1252", `<?xml version="1.0" encoding="windows-1252"?>` +
+			"<rss><channel><item><title>Caf\xe9 \x93quoted\x94</title></item></channel></rss>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := decodeTestFeed(t, tt.doc, decodeOptions{}).Items
+			if len(items) != 1 || items[0].Title != "Café “quoted”" {
+				t.Errorf("items = %+v", items)
+			}
+		})
+	}
+}
+
+func TestCharsetReaderUnsupported(t *testing.T) {
+	if _, err := charsetReader("koi8-r", strings.NewReader("")); err == nil {
+		t.Error("charsetReader accepted koi8-r")
+	}
+}
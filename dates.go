@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultLocation is used for dates that carry no zone information, such as
+// "2006-01-02 15:04:05". It is set from --default-timezone before any date
+// is parsed.
+var defaultLocation = time.UTC
+
+var zonedDateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"Jan 2 2006 15:04:05 -0700",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04-07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05-07:00",
+	time.UnixDate,
+	time.RubyDate,
+}
+
+var naiveDateLayouts = []string{
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	time.ANSIC,
+}
+
+// fallbackDateLayouts are tried last, on the date as given. Like the RFC 1123
+// and RFC 822 parsing this tool always did, they accept any zone
+// abbreviation; one not in obsoleteZones, such as "JST", is read as UTC.
+var fallbackDateLayouts = []string{
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC822,
+	time.RFC822Z,
+}
+
+// obsoleteZones maps the zone names allowed by RFC 822 (and a few common
+// European ones) to numeric offsets. time.Parse accepts these names but
+// silently treats unknown ones as UTC, which shifts the date by hours.
+var obsoleteZones = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+}
+
+var englishMonthAbbrevs = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+var localeMonths = map[string][12]string{
+	"en": {"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	"nl": {"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+	"de": {"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	"fr": {"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	"es": {"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+}
+
+var localeMonthAbbrevs = map[string][12]string{
+	"en": englishMonthAbbrevs,
+	"nl": {"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+	"de": {"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+	"fr": {"janv", "févr", "mars", "avr", "mai", "juin", "juil", "août", "sept", "oct", "nov", "déc"},
+	"es": {"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sep", "oct", "nov", "dic"},
+}
+
+var localeWeekdays = map[string][7]string{
+	"en": {"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	"nl": {"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+	"de": {"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	"fr": {"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	"es": {"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+}
+
+// localeWeekdayAbbrevs are only used for formatting: several of them are
+// also month abbreviations in another locale, so they can't be parsed.
+var localeWeekdayAbbrevs = map[string][7]string{
+	"en": {"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	"nl": {"zo", "ma", "di", "wo", "do", "vr", "za"},
+	"de": {"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	"fr": {"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	"es": {"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+}
+
+// formatLocalDate formats t with a Go time layout such as "2 January 2006",
+// writing month and weekday names in locale ("en", "nl", "de", "fr" or
+// "es") instead of English.
+func formatLocalDate(t time.Time, layout, locale string) string {
+	names := []struct {
+		token string
+		name  string
+	}{
+		// Longer tokens first, so "January" isn't taken for "Jan".
+		{"January", localeMonths[locale][t.Month()-1]},
+		{"Monday", localeWeekdays[locale][t.Weekday()]},
+		{"Jan", localeMonthAbbrevs[locale][t.Month()-1]},
+		{"Mon", localeWeekdayAbbrevs[locale][t.Weekday()]},
+	}
+	var sb strings.Builder
+	chunkStart := 0
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, n := range names {
+			if strings.HasPrefix(layout[i:], n.token) {
+				sb.WriteString(t.Format(layout[chunkStart:i]))
+				sb.WriteString(n.name)
+				i += len(n.token)
+				chunkStart = i
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	sb.WriteString(t.Format(layout[chunkStart:]))
+	return sb.String()
+}
+
+// dateWords maps lowercased month and weekday names in every known locale to
+// the English month abbreviation they stand for; weekdays and filler words
+// map to "" and are dropped.
+var dateWords = buildDateWords()
+
+func buildDateWords() map[string]string {
+	words := map[string]string{"de": "", "del": "", "der": "", "den": ""}
+	for _, names := range localeWeekdays {
+		for _, name := range names {
+			words[strings.ToLower(name)] = ""
+		}
+	}
+	for _, name := range []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"} {
+		words[name] = ""
+	}
+	for _, names := range localeMonths {
+		for i, name := range names {
+			words[strings.ToLower(name)] = englishMonthAbbrevs[i]
+		}
+	}
+	for _, names := range localeMonthAbbrevs {
+		for i, name := range names {
+			words[strings.ToLower(name)] = englishMonthAbbrevs[i]
+		}
+	}
+	words["sept"] = "Sep"
+	return words
+}
+
+var (
+	epochPattern        = regexp.MustCompile(`^\d{9,13}$`)
+	weekdayPrefix       = regexp.MustCompile(`^[^\d,]+,\s*`)
+	dateWordPattern     = regexp.MustCompile(`\p{L}+\.?`)
+	trailingZonePattern = regexp.MustCompile(`\s*\(?([A-Z]{1,4})\)?$`)
+	zoneCommentPattern  = regexp.MustCompile(`([+-]\d{4})\s*\([^()]*\)$`)
+)
+
+func parseRSSDateUncached(dateStr string) (time.Time, error) {
+	original := dateStr
+	dateStr = strings.Join(strings.Fields(dateStr), " ")
+	// Mail-style dates may repeat the zone in a comment: "+0000 (UTC)".
+	dateStr = zoneCommentPattern.ReplaceAllString(dateStr, "$1")
+	if dateStr == "" {
+		return time.Time{}, fmt.Errorf("unable to parse date: %s", original)
+	}
+
+	if epochPattern.MatchString(dateStr) {
+		n, _ := strconv.ParseInt(dateStr, 10, 64)
+		if len(dateStr) == 13 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+
+	// RFC 3339 and ISO 8601 dates are the common case after RFC 1123, and
+	// must be tried before the normalization below rewrites a trailing "Z".
+	if t, err := time.Parse(time.RFC3339, dateStr); err == nil {
+		return t, nil
+	}
+
+	normalized := normalizeDateString(dateStr)
+	for _, layout := range zonedDateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range naiveDateLayouts {
+		if t, err := time.ParseInLocation(layout, normalized, defaultLocation); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range fallbackDateLayouts {
+		if t, err := time.Parse(layout, dateStr); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse date: %s", original)
+}
+
+// normalizeDateString rewrites a date into a shape the layouts above
+// understand: weekday names are dropped, localized month names become English
+// abbreviations, commas disappear, and obsolete zone names become offsets.
+func normalizeDateString(s string) string {
+	s = weekdayPrefix.ReplaceAllString(s, "")
+
+	if m := trailingZonePattern.FindStringSubmatchIndex(s); m != nil {
+		if offset, ok := obsoleteZones[s[m[2]:m[3]]]; ok {
+			s = s[:m[0]] + " " + offset
+		}
+	}
+
+	s = dateWordPattern.ReplaceAllStringFunc(s, func(word string) string {
+		replacement, ok := dateWords[strings.ToLower(strings.TrimSuffix(word, "."))]
+		if !ok {
+			return word
+		}
+		return replacement
+	})
+	s = strings.ReplaceAll(s, ",", " ")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// relativeAge describes how long before now t was, the way people say it:
+// "just now", "5 minutes ago", "yesterday", "last week", "3 months ago".
+func relativeAge(t, now time.Time, tr translations) string {
+	age := now.Sub(t)
+	days := int(age.Hours() / 24)
+	ago := func(n int, unit string) string {
+		if n == 1 {
+			return tr.get("1 " + unit + " ago")
+		}
+		return fmt.Sprintf(tr.get("%d "+unit+"s ago"), n)
+	}
+	switch {
+	case age < time.Minute:
+		return tr.get("just now")
+	case age < time.Hour:
+		return ago(int(age.Minutes()), "minute")
+	case age < 24*time.Hour:
+		return ago(int(age.Hours()), "hour")
+	case days < 2:
+		return tr.get("yesterday")
+	case days < 7:
+		return ago(days, "day")
+	case days < 14:
+		return tr.get("last week")
+	case days < 30:
+		return ago(days/7, "week")
+	case days < 60:
+		return tr.get("last month")
+	case days < 365:
+		return ago(days/30, "month")
+	case days < 730:
+		return tr.get("last year")
+	}
+	return ago(days/365, "year")
+}
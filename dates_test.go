@@ -0,0 +1,155 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRSSDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Mon, 12 Oct 2026 10:00:00 +0000", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"Mon, 12 Oct 2026 10:00:00 GMT", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"Mon, 12 Oct 2026 12:00:00 CEST", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"Mon,  12 Oct 2026   10:00 +0200", time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)},
+		{"Mon, 12 Oct 2026 10:00:00 +0000 (UTC)", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"Mon, 12 Oct 2026 12:00:00 +0200 (CEST)", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		// Unknown zone abbreviations are read as UTC rather than rejected.
+		{"Mon, 12 Oct 2026 10:00:00 JST", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"Mon, 12 Oct 2026 10:00:00 AEST", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"Mon, 12 Oct 2026 10:00:00 IST", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"12 Oct 26 10:00 JST", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"2026-10-12T10:00:00Z", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"2026-10-12T12:00:00+02:00", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"2026-10-12", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{"2026-10-12 10:00:00", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"maandag 12 oktober 2026 10:00", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)},
+		{"1791800000", time.Unix(1791800000, 0).UTC()},
+		{"1791800000000", time.UnixMilli(1791800000000).UTC()},
+	}
+	for _, tt := range tests {
+		got, err := parseRSSDate(tt.in)
+		if err != nil {
+			t.Errorf("parseRSSDate(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseRSSDate(%q) = %s, want %s", tt.in, got.UTC(), tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "   ", "not a date", "32 Foo 2026"} {
+		if _, err := parseRSSDate(in); err == nil {
+			t.Errorf("parseRSSDate(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseRSSDateDefaultLocation(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	defer func(location *time.Location) { defaultLocation = location }(defaultLocation)
+
+	const naive = "2026-10-12 10:00:00"
+	defaultLocation = time.UTC
+	inUTC, err := parseRSSDate(naive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The cached UTC result must not be returned for another location.
+	defaultLocation = amsterdam
+	inAmsterdam, err := parseRSSDate(naive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inAmsterdam.Sub(inUTC) != -2*time.Hour {
+		t.Errorf("%q parsed as %s in UTC and %s in Amsterdam, want two hours apart", naive, inUTC, inAmsterdam)
+	}
+}
+
+func TestParseRSSDateCacheBounded(t *testing.T) {
+	for i := 0; i < maxCachedDates+10; i++ {
+		parseRSSDate(time.Unix(int64(1791800000+i), 0).UTC().Format(time.RFC3339))
+	}
+	dateCacheMu.Lock()
+	size := len(dateCache)
+	dateCacheMu.Unlock()
+	if size > maxCachedDates {
+		t.Errorf("dateCache holds %d dates, want at most %d", size, maxCachedDates)
+	}
+}
+
+func TestFormatLocalDate(t *testing.T) {
+	date := time.Date(2026, 10, 12, 9, 5, 0, 0, time.UTC)
+	tests := []struct {
+		layout, locale, want string
+	}{
+		{"2 January 2006", "en", "12 October 2026"},
+		{"2 January 2006", "nl", "12 oktober 2026"},
+		{"Mon 2 Jan", "de", "Mo 12 Okt"},
+		{"Monday 2 January 15:04", "fr", "lundi 12 octobre 09:05"},
+		{"Jan 2, 2006", "es", "oct 12, 2026"},
+	}
+	for _, tt := range tests {
+		if got := formatLocalDate(date, tt.layout, tt.locale); got != tt.want {
+			t.Errorf("formatLocalDate(%q, %s) = %q, want %q", tt.layout, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeAge(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	nl := builtinTranslations["nl"]
+	tests := []struct {
+		age  time.Duration
+		tr   translations
+		want string
+	}{
+		{30 * time.Second, nil, "just now"},
+		{time.Minute, nil, "1 minute ago"},
+		{5 * time.Hour, nil, "5 hours ago"},
+		{30 * time.Hour, nil, "yesterday"},
+		{3 * 24 * time.Hour, nil, "3 days ago"},
+		{3 * 24 * time.Hour, nl, "3 dagen geleden"},
+	}
+	for _, tt := range tests {
+		if got := relativeAge(now.Add(-tt.age), now, tt.tr); got != tt.want {
+			t.Errorf("relativeAge(%s) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	lastRun := time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"0", time.Time{}},
+		{"", time.Time{}},
+		{"7", now.AddDate(0, 0, -7)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"36h", now.Add(-36 * time.Hour)},
+		{"last-run", lastRun},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.in, now, runState{LastSuccessfulRun: lastRun})
+		if err != nil {
+			t.Errorf("parseSince(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"-3", "-1h", "soon"} {
+		if _, err := parseSince(in, now, runState{}); err == nil {
+			t.Errorf("parseSince(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type diffEntry struct {
+	Key    string   `json:"key"`
+	Title  string   `json:"title"`
+	Link   string   `json:"link"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+type feedDiff struct {
+	Added   []diffEntry `json:"added"`
+	Removed []diffEntry `json:"removed"`
+	Changed []diffEntry `json:"changed"`
+}
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format: 'text' or 'json'")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [flags] <feed A> <feed B>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		fatalf("Error: --format must be 'text' or 'json'")
+	}
+
+	var feeds [2][]Item
+	for i, source := range fs.Args() {
+		items, err := loadFeedItems(source)
+		if err != nil {
+			fatalf("Error loading %s: %v", source, err)
+		}
+		feeds[i] = items
+	}
+
+	result := diffItems(feeds[0], feeds[1])
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(result)
+		return
+	}
+	for _, entry := range result.Added {
+		fmt.Printf("+ %s (%s)\n", entry.Title, entry.Key)
+	}
+	for _, entry := range result.Removed {
+		fmt.Printf("- %s (%s)\n", entry.Title, entry.Key)
+	}
+	for _, entry := range result.Changed {
+		fmt.Printf("~ %s (%s): %s\n", entry.Title, entry.Key, strings.Join(entry.Fields, ", "))
+	}
+	fmt.Printf("%d added, %d removed, %d changed\n", len(result.Added), len(result.Removed), len(result.Changed))
+}
+
+func loadFeedItems(source string) ([]Item, error) {
+	r, err := openFeed(source)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	channel, err := decodeFeed(r, decodeOptions{maxBytes: defaultMaxFeedBytes})
+	if err != nil {
+		return nil, err
+	}
+	return channel.Items, nil
+}
+
+// itemKey identifies an item across feeds: its GUID when it has one,
+// otherwise its link, both normalized.
+func itemKey(item Item) string {
+	if key := normalizeURL(item.GUID.Value); key != "" {
+		return key
+	}
+	return normalizeURL(item.Link)
+}
+
+func newDiffEntry(item Item) diffEntry {
+	key := item.GUID.Value
+	if key == "" {
+		key = item.Link
+	}
+	return diffEntry{Key: key, Title: item.Title, Link: item.Link}
+}
+
+func diffItems(before, after []Item) feedDiff {
+	var result feedDiff
+	beforeByKey := make(map[string]Item)
+	for _, item := range before {
+		beforeByKey[itemKey(item)] = item
+	}
+	afterKeys := make(map[string]bool)
+	for _, item := range after {
+		key := itemKey(item)
+		afterKeys[key] = true
+		old, ok := beforeByKey[key]
+		if !ok {
+			result.Added = append(result.Added, newDiffEntry(item))
+			continue
+		}
+		if fields := changedFields(old, item); len(fields) > 0 {
+			entry := newDiffEntry(item)
+			entry.Fields = fields
+			result.Changed = append(result.Changed, entry)
+		}
+	}
+	for _, item := range before {
+		if !afterKeys[itemKey(item)] {
+			result.Removed = append(result.Removed, newDiffEntry(item))
+		}
+	}
+	return result
+}
+
+func changedFields(a, b Item) []string {
+	var fields []string
+	compare := func(name, x, y string) {
+		if strings.TrimSpace(x) != strings.TrimSpace(y) {
+			fields = append(fields, name)
+		}
+	}
+	compare("title", a.Title, b.Title)
+	compare("link", a.Link, b.Link)
+	compare("pubDate", a.PubDate, b.PubDate)
+	compare("authors", strings.Join(a.authors(), "\n"), strings.Join(b.authors(), "\n"))
+	compare("description", a.Description, b.Description)
+	compare("content", a.Content, b.Content)
+	compare("categories", strings.Join(a.Categories, "\n"), strings.Join(b.Categories, "\n"))
+	return fields
+}
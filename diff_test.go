@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestItemKey(t *testing.T) {
+	tests := []struct {
+		item Item
+		want string
+	}{
+		{Item{GUID: GUID{Value: "https://Example.com/p/"}, Link: "https://example.com/other"}, "//example.com/p"},
+		{Item{GUID: GUID{Value: "urn:uuid:1"}}, "urn:uuid:1"},
+		{Item{Link: "https://example.com/p?utm_medium=rss"}, "//example.com/p"},
+		{Item{}, ""},
+	}
+	for _, tt := range tests {
+		if got := itemKey(tt.item); got != tt.want {
+			t.Errorf("itemKey(%+v) = %q, want %q", tt.item, got, tt.want)
+		}
+	}
+}
+
+func TestDiffItems(t *testing.T) {
+	before := []Item{
+		{GUID: GUID{Value: "1"}, Title: "Same", Description: "text"},
+		{GUID: GUID{Value: "2"}, Title: "Edited", Description: "old", Creators: []string{"Alice"}},
+		{Link: "https://example.com/gone", Title: "Gone"},
+	}
+	after := []Item{
+		{GUID: GUID{Value: "1"}, Title: "Same ", Description: "text"},
+		{GUID: GUID{Value: "2"}, Title: "Edited!", Description: "new", Author: "Alice"},
+		{Link: "https://example.com/new", Title: "New"},
+	}
+	got := diffItems(before, after)
+	want := feedDiff{
+		Added:   []diffEntry{{Key: "https://example.com/new", Title: "New", Link: "https://example.com/new"}},
+		Removed: []diffEntry{{Key: "https://example.com/gone", Title: "Gone", Link: "https://example.com/gone"}},
+		Changed: []diffEntry{{Key: "2", Title: "Edited!", Fields: []string{"title", "description"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffItems = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadFeedItemsSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<rss><channel><item><title>Huge</title><description>")
+		chunk := bytes.Repeat([]byte("a"), 1<<20)
+		for written := 0; written <= defaultMaxFeedBytes; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+		io.WriteString(w, "</description></item></channel></rss>")
+	}))
+	defer server.Close()
+
+	if _, err := loadFeedItems(server.URL); !errors.Is(err, errFeedTooLarge) {
+		t.Errorf("loadFeedItems of an oversized feed = %v, want %v", err, errFeedTooLarge)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type digestPeriod struct {
+	key   string // file name stem and sort key, e.g. "2026-W42" or "2026-10"
+	title string
+	start time.Time
+	items []Item
+}
+
+// bucketByPeriod groups items by ISO week or calendar month of their
+// publication date, in --default-timezone. Items keep their order within a
+// period; periods are newest first unless opts.newestFirst is false. Undated
+// items are collected in a final "Undated" period.
+func bucketByPeriod(items []Item, digest string, opts markdownOptions) []*digestPeriod {
+	periods := make(map[string]*digestPeriod)
+	var undated *digestPeriod
+	for _, item := range items {
+		pubDate, err := parseRSSDate(item.PubDate)
+		if err != nil {
+			if undated == nil {
+				undated = &digestPeriod{key: "undated", title: opts.tr.get("Undated")}
+			}
+			undated.items = append(undated.items, item)
+			continue
+		}
+		key, title, start := periodOf(pubDate.In(defaultLocation), digest, opts)
+		period, ok := periods[key]
+		if !ok {
+			period = &digestPeriod{key: key, title: title, start: start}
+			periods[key] = period
+		}
+		period.items = append(period.items, item)
+	}
+
+	ordered := make([]*digestPeriod, 0, len(periods)+1)
+	for _, period := range periods {
+		ordered = append(ordered, period)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if opts.newestFirst {
+			return ordered[i].start.After(ordered[j].start)
+		}
+		return ordered[i].start.Before(ordered[j].start)
+	})
+	if undated != nil {
+		ordered = append(ordered, undated)
+	}
+	return ordered
+}
+
+// periodOf returns the digest period t falls in, with a title in the
+// language and date locale of opts.
+func periodOf(t time.Time, digest string, opts markdownOptions) (key, title string, start time.Time) {
+	if digest == "monthly" {
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start.Format("2006-01"), formatLocalDate(start, opts.tr.get("January 2006"), opts.dateLocale), start
+	}
+	// Weeks start on Monday, as ISO 8601 weeks do.
+	offset := (int(t.Weekday()) + 6) % 7
+	start = time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	year, week := start.ISOWeek()
+	end := start.AddDate(0, 0, 6)
+	key = fmt.Sprintf("%d-W%02d", year, week)
+	dayLayout := opts.tr.get("Jan 2")
+	title = fmt.Sprintf(opts.tr.get("Week %d, %d (%s – %s)"), week, year,
+		formatLocalDate(start, dayLayout, opts.dateLocale), formatLocalDate(end, dayLayout, opts.dateLocale))
+	return key, title, start
+}
+
+// outputDigest writes one "## period" section per digest period.
+func outputDigest(w io.Writer, items []Item, digest string, opts markdownOptions) {
+	items = cleanItems(items)
+	opts.headingLevel = 3
+	for i, period := range bucketByPeriod(items, digest, opts) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## %s\n\n", period.title)
+		outputMarkdown(w, period.items, opts)
+	}
+}
+
+// writeDigestFiles writes every digest period to its own file in dir, named
+// after the period (2026-W42.md, 2026-10.md, undated.md). It returns the
+// number of files written.
+func writeDigestFiles(dir string, items []Item, digest string, opts markdownOptions) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	items = cleanItems(items)
+	opts.headingLevel = 2
+	periods := bucketByPeriod(items, digest, opts)
+	for _, period := range periods {
+		file, err := os.Create(filepath.Join(dir, period.key+".md"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create digest file: %w", err)
+		}
+		fmt.Fprintf(file, "# %s\n\n", period.title)
+		outputMarkdown(file, period.items, opts)
+		if err := file.Close(); err != nil {
+			return 0, fmt.Errorf("failed to write digest file: %w", err)
+		}
+	}
+	return len(periods), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBucketByPeriod(t *testing.T) {
+	items := []Item{
+		{Title: "Jan 4", PubDate: "Mon, 04 Jan 2027 09:00:00 +0000"},
+		{Title: "Jan 3", PubDate: "Sun, 03 Jan 2027 23:00:00 +0000"},
+		{Title: "Dec 28", PubDate: "Mon, 28 Dec 2026 00:00:00 +0000"},
+		{Title: "undated"},
+		{Title: "Dec 1", PubDate: "Tue, 01 Dec 2026 10:00:00 +0000"},
+	}
+	tests := []struct {
+		digest      string
+		newestFirst bool
+		want        []string // "key: titles"
+	}{
+		{"weekly", true, []string{"2027-W01: Jan 4", "2026-W53: Jan 3, Dec 28", "2026-W49: Dec 1", "undated: undated"}},
+		{"weekly", false, []string{"2026-W49: Dec 1", "2026-W53: Jan 3, Dec 28", "2027-W01: Jan 4", "undated: undated"}},
+		{"monthly", true, []string{"2027-01: Jan 4, Jan 3", "2026-12: Dec 28, Dec 1", "undated: undated"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, period := range bucketByPeriod(items, tt.digest, markdownOptions{newestFirst: tt.newestFirst}) {
+			var titles []string
+			for _, item := range period.items {
+				titles = append(titles, item.Title)
+			}
+			got = append(got, period.key+": "+strings.Join(titles, ", "))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s (newestFirst=%v) = %q, want %q", tt.digest, tt.newestFirst, got, tt.want)
+		}
+	}
+}
+
+func TestPeriodTitles(t *testing.T) {
+	item := []Item{{Title: "Post", PubDate: "Wed, 14 Oct 2026 10:00:00 +0000"}}
+	tests := []struct {
+		digest string
+		opts   markdownOptions
+		want   string
+	}{
+		{"weekly", markdownOptions{dateLocale: "en"}, "Week 42, 2026 (Oct 12 – Oct 18)"},
+		{"monthly", markdownOptions{dateLocale: "en"}, "October 2026"},
+		{"weekly", markdownOptions{dateLocale: "nl", tr: builtinTranslations["nl"]}, "Week 42, 2026 (12 okt – 18 okt)"},
+		{"monthly", markdownOptions{dateLocale: "nl", tr: builtinTranslations["nl"]}, "oktober 2026"},
+	}
+	for _, tt := range tests {
+		if got := bucketByPeriod(item, tt.digest, tt.opts)[0].title; got != tt.want {
+			t.Errorf("%s title = %q, want %q", tt.digest, got, tt.want)
+		}
+	}
+}
+
+func TestWriteDigestFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "digests")
+	items := []Item{
+		{Title: "New", Link: "https://example.com/2", PubDate: "Wed, 14 Oct 2026 10:00:00 +0000"},
+		{Title: "Old", Link: "https://example.com/1", PubDate: "Tue, 15 Sep 2026 10:00:00 +0000"},
+		{Title: "Undated", Link: "https://example.com/0"},
+	}
+	n, err := writeDigestFiles(dir, items, "monthly", markdownOptions{newestFirst: true, dateLocale: "en"})
+	if err != nil || n != 3 {
+		t.Fatalf("writeDigestFiles = %d, %v; want 3 files", n, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "2026-10.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# October 2026\n\n- [New](https://example.com/2) - Unknown\n"; string(data) != want {
+		t.Errorf("2026-10.md = %q, want %q", data, want)
+	}
+	for _, name := range []string{"2026-09.md", "undated.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxPageBytes = 5 << 20
+
+var knownEnrichers = map[string]func(item *Item, page string){
+	"canonical": enrichCanonical,
+	"fulltext":  enrichFullText,
+	"opengraph": enrichOpenGraph,
+}
+
+type enrichOptions struct {
+	enrichers []string
+	workers   int
+	perHost   int
+
+	// robots, when set, is consulted before every fetch and disallowed
+	// pages are skipped.
+	robots *robotsCache
+}
+
+func parseEnrichers(spec string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := knownEnrichers[name]; !ok {
+			return nil, fmt.Errorf("unknown enricher %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// enrichItems fetches every item's link at most once and runs the configured
+// enrichers on the page. Items are processed by a bounded pool of workers, and
+// no more than perHost requests hit the same host at the same time.
+func enrichItems(items []Item, opts enrichOptions) {
+	if len(opts.enrichers) == 0 || len(items) == 0 {
+		return
+	}
+	workers := opts.workers
+	if workers < 1 {
+		workers = 1
+	}
+	perHost := opts.perHost
+	if perHost < 1 {
+		perHost = 1
+	}
+
+	var hostsMu sync.Mutex
+	hostSlots := make(map[string]chan struct{})
+	acquire := func(host string) chan struct{} {
+		hostsMu.Lock()
+		defer hostsMu.Unlock()
+		slots, ok := hostSlots[host]
+		if !ok {
+			slots = make(chan struct{}, perHost)
+			hostSlots[host] = slots
+		}
+		return slots
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item := &items[i]
+				u, err := url.Parse(item.Link)
+				if err != nil || u.Host == "" {
+					continue
+				}
+				slots := acquire(u.Host)
+				slots <- struct{}{}
+				if opts.robots != nil && !opts.robots.allowed(item.Link) {
+					<-slots
+					infof("Skipping enrichment of %s: disallowed by robots.txt", item.Link)
+					continue
+				}
+				page, err := fetchPage(item.Link)
+				<-slots
+				if err != nil {
+					warnf("enriching %s: %v", item.Link, err)
+					continue
+				}
+				for _, name := range opts.enrichers {
+					knownEnrichers[name](item, page)
+				}
+			}
+		}()
+	}
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	debugf("Enriched %d items with %s", len(items), strings.Join(opts.enrichers, ", "))
+}
+
+func fetchPage(pageURL string) (string, error) {
+	resp, err := httpGet(pageURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func enrichCanonical(item *Item, page string) {
+	for _, attrs := range htmlTags(page, "link") {
+		if !strings.EqualFold(attrs["rel"], "canonical") || attrs["href"] == "" {
+			continue
+		}
+		if resolved := resolveURL(item.Link, attrs["href"]); resolved != "" {
+			item.Link = resolved
+		}
+		return
+	}
+}
+
+func enrichFullText(item *Item, page string) {
+	if strings.TrimSpace(item.Content) != "" {
+		return
+	}
+	for _, tag := range []string{"article", "main"} {
+		if inner := htmlElementInner(page, tag); strings.TrimSpace(inner) != "" {
+			item.Content = strings.TrimSpace(inner)
+			return
+		}
+	}
+}
+
+// enrichOpenGraph fills in what the feed left out from the page's
+// OpenGraph (and Twitter card) metadata: a thumbnail from og:image, the
+// description, the publication date, the title (falling back to <title>)
+// and the author. Fields the feed already has are kept.
+func enrichOpenGraph(item *Item, page string) {
+	meta := make(map[string]string)
+	for _, attrs := range htmlTags(page, "meta") {
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+		if content := strings.TrimSpace(attrs["content"]); key != "" && content != "" {
+			if _, seen := meta[key]; !seen {
+				meta[key] = content
+			}
+		}
+	}
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := meta[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	if item.image() == "" {
+		if image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image"); image != "" {
+			if resolved := resolveURL(item.Link, image); resolved != "" {
+				item.Extra = append(item.Extra, RawElement{
+					Name:  xml.Name{Space: mediaNamespace, Local: "thumbnail"},
+					Attrs: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: resolved}},
+				})
+			}
+		}
+	}
+	if strings.TrimSpace(item.Description) == "" {
+		if description := first("og:description", "twitter:description", "description"); description != "" {
+			item.Description = html.EscapeString(description)
+		}
+	}
+	if strings.TrimSpace(item.PubDate) == "" {
+		if published := first("article:published_time", "og:published_time", "published_time"); published != "" {
+			if t, err := parseRSSDate(published); err == nil {
+				item.PubDate = t.Format(time.RFC1123Z)
+			}
+		}
+	}
+	if strings.TrimSpace(item.Title) == "" {
+		item.Title = first("og:title", "twitter:title")
+	}
+	if strings.TrimSpace(item.Title) == "" {
+		item.Title = strings.TrimSpace(html.UnescapeString(htmlElementInner(page, "title")))
+	}
+	if len(item.authors()) == 0 {
+		if author := first("author", "twitter:creator"); author != "" {
+			item.Creators = append(item.Creators, author)
+		}
+	}
+}
+
+var (
+	htmlAttrPattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	htmlTagPatterns sync.Map // tag name -> *regexp.Regexp
+)
+
+// htmlTags returns the attributes of every <tag ...> in page. It is a
+// deliberately small scanner, not an HTML parser: it's only used to pick
+// <link> and <meta> elements out of article heads.
+func htmlTags(page, tag string) []map[string]string {
+	pattern, ok := htmlTagPatterns.Load(tag)
+	if !ok {
+		pattern, _ = htmlTagPatterns.LoadOrStore(tag, regexp.MustCompile(`(?i)<`+regexp.QuoteMeta(tag)+`\b[^>]*>`))
+	}
+
+	var tags []map[string]string
+	for _, match := range pattern.(*regexp.Regexp).FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range htmlAttrPattern.FindAllStringSubmatch(match, -1) {
+			value := strings.Trim(attr[2], `"'`)
+			attrs[strings.ToLower(attr[1])] = html.UnescapeString(value)
+		}
+		tags = append(tags, attrs)
+	}
+	return tags
+}
+
+func htmlElementInner(page, tag string) string {
+	pattern := regexp.MustCompile(`(?is)<` + regexp.QuoteMeta(tag) + `\b[^>]*>(.*?)</` + regexp.QuoteMeta(tag) + `\s*>`)
+	match := pattern.FindStringSubmatch(page)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func resolveURL(base, ref string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
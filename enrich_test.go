@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnrichItemsBoundsConcurrencyPerHost(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		fmt.Fprintf(w, `<html><head><link rel="canonical" href="/canonical%s"></head></html>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	items := make([]Item, 12)
+	for i := range items {
+		items[i].Link = fmt.Sprintf("%s/%d", server.URL, i)
+	}
+	items = append(items, Item{Link: "not a url"})
+	enrichItems(items, enrichOptions{enrichers: []string{"canonical"}, workers: 6, perHost: 2})
+
+	if maxActive > 2 {
+		t.Errorf("%d concurrent requests to one host, want at most 2", maxActive)
+	}
+	for i, item := range items[:12] {
+		if want := fmt.Sprintf("%s/canonical/%d", server.URL, i); item.Link != want {
+			t.Errorf("item %d link = %q, want %q", i, item.Link, want)
+		}
+	}
+}
+
+func TestParseEnrichers(t *testing.T) {
+	if names, err := parseEnrichers(" canonical, ,opengraph"); err != nil || len(names) != 2 {
+		t.Errorf("parseEnrichers = %q, %v", names, err)
+	}
+	if _, err := parseEnrichers("canonical,readability"); err == nil {
+		t.Error("parseEnrichers accepted an unknown enricher")
+	}
+}
+
+func TestEnrichOpenGraph(t *testing.T) {
+	page := `<html><head>
+<title>Page &amp; title</title>
+<meta property="og:title" content="OG title">
+<meta property="og:image" content="/img/cover.png">
+<meta property="og:image" content="/img/second.png">
+<meta name="description" content="Plain description">
+<meta property="og:description" content="Fish &amp; chips">
+<meta property="article:published_time" content="2026-10-12T10:00:00Z">
+<meta name="author" content="Jane Doe">
+</head></html>`
+	tests := []struct {
+		name  string
+		item  Item
+		check func(t *testing.T, item Item)
+	}{
+		{
+			name: "fills missing fields",
+			item: Item{Link: "https://example.com/posts/1"},
+			check: func(t *testing.T, item Item) {
+				checks := []struct{ field, got, want string }{
+					{"title", item.Title, "OG title"},
+					{"description", item.Description, "Fish &amp; chips"},
+					{"pubDate", item.PubDate, "Mon, 12 Oct 2026 10:00:00 +0000"},
+					{"image", item.image(), "https://example.com/img/cover.png"},
+				}
+				for _, c := range checks {
+					if c.got != c.want {
+						t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+					}
+				}
+				if authors := item.authors(); len(authors) != 1 || authors[0] != "Jane Doe" {
+					t.Errorf("authors = %q", authors)
+				}
+			},
+		},
+		{
+			name: "keeps what the feed has",
+			item: Item{
+				Link: "https://example.com/posts/1", Title: "Feed title", Description: "Feed description",
+				PubDate: "Sun, 11 Oct 2026 10:00:00 +0000", Author: "Bob",
+				Extra: []RawElement{{Name: xml.Name{Space: mediaNamespace, Local: "thumbnail"}, Attrs: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: "https://example.com/feed.png"}}}},
+			},
+			check: func(t *testing.T, item Item) {
+				if item.Title != "Feed title" || item.Description != "Feed description" ||
+					item.PubDate != "Sun, 11 Oct 2026 10:00:00 +0000" || item.image() != "https://example.com/feed.png" ||
+					len(item.Creators) != 0 || len(item.Extra) != 1 {
+					t.Errorf("item changed: %+v", item)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := tt.item
+			enrichOpenGraph(&item, page)
+			tt.check(t, item)
+		})
+	}
+
+	item := Item{Link: "https://example.com/"}
+	enrichOpenGraph(&item, `<title>Only &amp; title</title>`)
+	if item.Title != "Only & title" {
+		t.Errorf("title = %q, want the <title> fallback", item.Title)
+	}
+}
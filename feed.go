@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	maxXMLDepth       = 64
+	maxXMLAttrLength  = 64 << 10
+	maxXMLAttrPerElem = 256
+
+	// defaultMaxFeedBytes is the --max-feed-bytes default, also used by the
+	// subcommands that read feeds.
+	defaultMaxFeedBytes = 50 << 20
+)
+
+var errFeedTooLarge = errors.New("feed exceeds the maximum allowed size")
+
+type decodeOptions struct {
+	// cutoff and lookahead stop decoding once lookahead consecutive dated
+	// items are older than cutoff. Most feeds are newest-first, so the rest of
+	// the document would be filtered out anyway.
+	cutoff    time.Time
+	lookahead int
+
+	// maxItems stops decoding once this many items have been read, so huge
+	// feeds are never fully materialized. Zero means no limit.
+	maxItems int
+
+	// maxBytes fails decoding of documents larger than this. Zero means no
+	// limit.
+	maxBytes int64
+
+	// recover repairs stray ampersands, invalid control characters, unknown
+	// entities and mismatched tags instead of failing on them.
+	recover bool
+}
+
+const (
+	atomNamespace    = "http://www.w3.org/2005/Atom"
+	rss1Namespace    = "http://purl.org/rss/1.0/"
+	dcNamespace      = "http://purl.org/dc/elements/1.1/"
+	contentNamespace = "http://purl.org/rss/1.0/modules/content/"
+)
+
+// UnmarshalXML decodes an RSS item. RSS elements are matched by namespace
+// as well as name: an unqualified <author> is the RSS author, but
+// <itunes:author> is an extension element and goes to Extra like any other.
+func (item *Item) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			if err := item.decodeElement(d, t); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (item *Item) decodeElement(d *xml.Decoder, start xml.StartElement) error {
+	var field *string
+	switch start.Name.Space {
+	case "", rss1Namespace:
+		switch start.Name.Local {
+		case "title":
+			field = &item.Title
+		case "link":
+			field = &item.Link
+		case "pubDate":
+			field = &item.PubDate
+		case "author":
+			field = &item.Author
+		case "description":
+			field = &item.Description
+		case "guid":
+			return d.DecodeElement(&item.GUID, &start)
+		case "category":
+			var category string
+			if err := d.DecodeElement(&category, &start); err != nil {
+				return err
+			}
+			item.Categories = append(item.Categories, category)
+			return nil
+		}
+	case dcNamespace:
+		if start.Name.Local == "creator" {
+			var creator string
+			if err := d.DecodeElement(&creator, &start); err != nil {
+				return err
+			}
+			item.Creators = append(item.Creators, creator)
+			return nil
+		}
+	case contentNamespace:
+		if start.Name.Local == "encoded" {
+			field = &item.Content
+		}
+	}
+	if field != nil {
+		return d.DecodeElement(field, &start)
+	}
+	var extra RawElement
+	if err := d.DecodeElement(&extra, &start); err != nil {
+		return err
+	}
+	item.Extra = append(item.Extra, extra)
+	return nil
+}
+
+// decodeFeed streams through an RSS or Atom document, collecting channel
+// metadata and items. Items are decoded one at a time so decoding can stop
+// early. Atom entries are mapped onto the RSS item model.
+func decodeFeed(r io.Reader, opts decodeOptions) (Channel, error) {
+	var channel Channel
+	if opts.maxBytes > 0 {
+		r = &sizeLimitedReader{r: r, remaining: opts.maxBytes}
+	}
+	r, err := normalizeFeedStart(r)
+	if err != nil {
+		return channel, err
+	}
+	if opts.recover {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return channel, err
+		}
+		r = bytes.NewReader(repairXML(data))
+	}
+
+	raw := xml.NewDecoder(r)
+	raw.CharsetReader = charsetReader
+	if opts.recover {
+		raw.Strict = false
+		raw.Entity = xml.HTMLEntity
+	}
+	decoder := xml.NewTokenDecoder(&guardedTokenReader{raw: raw, balance: opts.recover})
+
+	var parents []string
+	var atom atomFeedState
+	isAtom := false
+	olderInARow := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return channel, err
+		}
+		if _, ok := token.(xml.EndElement); ok && len(parents) > 0 {
+			parents = parents[:len(parents)-1]
+			continue
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var item Item
+		switch {
+		case start.Name.Local == "item":
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				return channel, err
+			}
+		case start.Name.Space == atomNamespace && start.Name.Local == "entry":
+			var entry atomEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return channel, err
+			}
+			item = entry.item()
+		case len(parents) > 0 && parents[len(parents)-1] == "channel":
+			if err := decodeChannelElement(decoder, start, &channel); err != nil {
+				return channel, err
+			}
+			continue
+		case len(parents) == 1 && isAtom:
+			if err := decodeAtomFeedElement(decoder, start, &channel, &atom); err != nil {
+				return channel, err
+			}
+			continue
+		default:
+			if len(parents) == 0 && start.Name.Space == atomNamespace && start.Name.Local == "feed" {
+				isAtom = true
+				for _, attr := range start.Attr {
+					if attr.Name.Local == "lang" && (attr.Name.Space == "xml" || attr.Name.Space == "http://www.w3.org/XML/1998/namespace") {
+						channel.Language = attr.Value
+					}
+				}
+			}
+			parents = append(parents, start.Name.Local)
+			continue
+		}
+
+		channel.Items = append(channel.Items, item)
+		if opts.maxItems > 0 && len(channel.Items) >= opts.maxItems {
+			break
+		}
+
+		if opts.cutoff.IsZero() || opts.lookahead <= 0 {
+			continue
+		}
+		pubDate, err := parseRSSDate(item.PubDate)
+		if err != nil {
+			continue // undated items neither extend nor break the run
+		}
+		if pubDate.Before(opts.cutoff) {
+			olderInARow++
+			if olderInARow >= opts.lookahead {
+				break
+			}
+		} else {
+			olderInARow = 0
+		}
+	}
+	if isAtom {
+		applyAtomFallbacks(&channel, atom)
+	}
+	return channel, nil
+}
+
+func decodeChannelElement(decoder *xml.Decoder, start xml.StartElement, channel *Channel) error {
+	if start.Name.Space == atomNamespace && start.Name.Local == "link" {
+		var link AtomLink
+		if err := decoder.DecodeElement(&link, &start); err != nil {
+			return err
+		}
+		channel.AtomLinks = append(channel.AtomLinks, link)
+		return nil
+	}
+	if start.Name.Space != "" {
+		return decoder.Skip()
+	}
+	switch start.Name.Local {
+	case "title":
+		return decoder.DecodeElement(&channel.Title, &start)
+	case "link":
+		return decoder.DecodeElement(&channel.Link, &start)
+	case "description":
+		return decoder.DecodeElement(&channel.Description, &start)
+	case "language":
+		return decoder.DecodeElement(&channel.Language, &start)
+	case "generator":
+		return decoder.DecodeElement(&channel.Generator, &start)
+	case "image":
+		channel.Image = &Image{}
+		return decoder.DecodeElement(channel.Image, &start)
+	}
+	return decoder.Skip()
+}
+
+// guardedTokenReader sits between the byte-level decoder and the one items
+// are decoded with, rejecting documents that declare their own entities
+// (the classic "billion laughs" shape) or nest or attribute themselves
+// beyond reasonable limits.
+type guardedTokenReader struct {
+	raw   *xml.Decoder
+	depth int
+
+	// balance repairs mismatched tags for --recover-xml: an end tag closes
+	// the elements left open inside it, stray end tags are dropped, and
+	// elements still open at the end of the document are closed. The raw
+	// tokens aren't checked for nesting, so without this the decoder reading
+	// them would still fail.
+	balance bool
+	open    []xml.Name
+	pending []xml.Token
+}
+
+func (g *guardedTokenReader) Token() (xml.Token, error) {
+	if len(g.pending) > 0 {
+		token := g.pending[0]
+		g.pending = g.pending[1:]
+		return token, nil
+	}
+	token, err := g.raw.RawToken()
+	if err == io.EOF && g.balance && len(g.open) > 0 {
+		return g.closeTo(0), nil
+	}
+	if err != nil {
+		return token, err
+	}
+	if g.balance {
+		switch t := token.(type) {
+		case xml.StartElement:
+			g.open = append(g.open, t.Name)
+		case xml.EndElement:
+			i := len(g.open) - 1
+			for i >= 0 && g.open[i] != t.Name {
+				i--
+			}
+			if i < 0 {
+				return g.Token()
+			}
+			if i < len(g.open)-1 {
+				// The end tag of an ancestor: close the elements
+				// inside it first.
+				first := g.closeTo(i + 1)
+				g.pending = append(g.pending, g.closeTo(i))
+				return first, nil
+			}
+			g.open = g.open[:i]
+		}
+	}
+	switch t := token.(type) {
+	case xml.StartElement:
+		g.depth++
+		if g.depth > maxXMLDepth {
+			return nil, fmt.Errorf("XML nesting deeper than %d elements", maxXMLDepth)
+		}
+		if len(t.Attr) > maxXMLAttrPerElem {
+			return nil, fmt.Errorf("element <%s> has more than %d attributes", t.Name.Local, maxXMLAttrPerElem)
+		}
+		for _, attr := range t.Attr {
+			if len(attr.Value) > maxXMLAttrLength {
+				return nil, fmt.Errorf("attribute %s on <%s> is longer than %d bytes", attr.Name.Local, t.Name.Local, maxXMLAttrLength)
+			}
+		}
+	case xml.EndElement:
+		g.depth--
+	case xml.Directive:
+		if bytes.Contains(bytes.ToUpper(t), []byte("ENTITY")) {
+			return nil, errors.New("feed declares custom XML entities, which are not allowed")
+		}
+	}
+	return token, nil
+}
+
+// closeTo closes the open elements from the innermost down to index i,
+// returning the first end tag and queueing the others.
+func (g *guardedTokenReader) closeTo(i int) xml.Token {
+	var ends []xml.Token
+	for j := len(g.open) - 1; j >= i; j-- {
+		ends = append(ends, xml.EndElement{Name: g.open[j]})
+		g.depth--
+	}
+	g.open = g.open[:i]
+	g.pending = append(ends[1:], g.pending...)
+	return ends[0]
+}
+
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, errFeedTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, errFeedTooLarge
+	}
+	return n, err
+}
+
+var strayAmpersand = regexp.MustCompile(`&([^&;\s<]{0,32};?)`)
+var xmlDeclEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*encoding=["']([^"']+)["'][^>]*\?>`)
+var entityRef = regexp.MustCompile(`^(#[0-9]+|#x[0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);$`)
+
+// repairXML fixes the malformations most often seen in hand-rolled feeds:
+// bare ampersands, control characters XML doesn't allow, and invalid UTF-8.
+// CDATA sections are left alone, since ampersands are legal there.
+func repairXML(data []byte) []byte {
+	if m := xmlDeclEncoding.FindSubmatchIndex(data); m != nil {
+		charset := string(data[m[2]:m[3]])
+		if transcoded, err := charsetReader(charset, bytes.NewReader(data[m[1]:])); err == nil {
+			if rest, err := io.ReadAll(transcoded); err == nil {
+				repaired := append([]byte(nil), data[:m[2]]...)
+				repaired = append(repaired, "UTF-8"...)
+				repaired = append(repaired, data[m[3]:m[1]]...)
+				data = append(repaired, rest...)
+			}
+		}
+	}
+	data = bytes.ToValidUTF8(data, []byte("\uFFFD"))
+	data = bytes.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF {
+			return r
+		}
+		return -1
+	}, data)
+
+	var out bytes.Buffer
+	for len(data) > 0 {
+		start := bytes.Index(data, []byte("<![CDATA["))
+		if start < 0 {
+			out.Write(escapeStrayAmpersands(data))
+			break
+		}
+		out.Write(escapeStrayAmpersands(data[:start]))
+		end := bytes.Index(data[start:], []byte("]]>"))
+		if end < 0 {
+			out.Write(data[start:])
+			break
+		}
+		end += start + len("]]>")
+		out.Write(data[start:end])
+		data = data[end:]
+	}
+	return out.Bytes()
+}
+
+func escapeStrayAmpersands(data []byte) []byte {
+	return strayAmpersand.ReplaceAllFunc(data, func(m []byte) []byte {
+		if entityRef.Match(m[1:]) {
+			return m
+		}
+		return append([]byte("&amp;"), m[1:]...)
+	})
+}
+
+// openFeed opens source as a URL when it has an http(s) scheme and as a
+// local file otherwise.
+func openFeed(source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+	resp, err := httpGet(source)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// nextPageURL returns the rel="next" link of channel, read from pageURL,
+// resolved against it. Paged and archived feeds (RFC 5005) use it to point to
+// the next, older page.
+func nextPageURL(channel Channel, pageURL string) string {
+	for _, link := range channel.AtomLinks {
+		if link.Rel != "next" || strings.TrimSpace(link.Href) == "" {
+			continue
+		}
+		base, err := url.Parse(pageURL)
+		if err != nil {
+			return ""
+		}
+		next, err := url.Parse(strings.TrimSpace(link.Href))
+		if err != nil {
+			return ""
+		}
+		return base.ResolveReference(next).String()
+	}
+	return ""
+}
+
+// followPagination walks the rel="next" links of channel, the first page
+// read from pageURL, appending the items of up to maxPages pages in total.
+// It stops early at a page it has seen before, once opts.maxItems items were
+// read, or when a page ends with an item older than opts.cutoff, since the
+// pages after it are older still.
+func followPagination(channel *Channel, pageURL string, maxPages int, opts decodeOptions) error {
+	visited := map[string]bool{pageURL: true}
+	page := *channel
+	for pages := 1; pages < maxPages; pages++ {
+		if opts.maxItems > 0 && len(channel.Items) >= opts.maxItems {
+			return nil
+		}
+		if n := len(page.Items); n > 0 && !opts.cutoff.IsZero() {
+			if date, err := parseRSSDate(page.Items[n-1].PubDate); err == nil && date.Before(opts.cutoff) {
+				return nil
+			}
+		}
+		next := nextPageURL(page, pageURL)
+		if next == "" || visited[next] {
+			return nil
+		}
+		visited[next] = true
+
+		r, err := openFeed(next)
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", next, err)
+		}
+		pageOpts := opts
+		if opts.maxItems > 0 {
+			pageOpts.maxItems = opts.maxItems - len(channel.Items)
+		}
+		page, err = decodeFeed(r, pageOpts)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", next, err)
+		}
+		debugf("Decoded %d items from page %d, %s", len(page.Items), pages+1, next)
+		channel.Items = append(channel.Items, page.Items...)
+		pageURL = next
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const itunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+func decodeTestFeed(t *testing.T, doc string, opts decodeOptions) Channel {
+	t.Helper()
+	channel, err := decodeFeed(strings.NewReader(doc), opts)
+	if err != nil {
+		t.Fatalf("decodeFeed: %v", err)
+	}
+	return channel
+}
+
+func TestDecodeFeedItemFields(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Podcast</title>
+    <item>
+      <title>Episode 1</title>
+      <itunes:title>Ep. 1</itunes:title>
+      <link>https://example.com/1</link>
+      <guid isPermaLink="false">ep-1</guid>
+      <pubDate>Mon, 12 Oct 2026 10:00:00 +0000</pubDate>
+      <itunes:author>Jane Doe</itunes:author>
+      <dc:creator>Alice</dc:creator>
+      <dc:creator>Bob</dc:creator>
+      <category>go</category>
+      <category>data</category>
+      <description>Summary</description>
+      <content:encoded><![CDATA[<p>Body</p>]]></content:encoded>
+      <enclosure url="https://example.com/1.mp3" length="1" type="audio/mpeg"/>
+    </item>
+  </channel>
+</rss>`
+	items := decodeTestFeed(t, doc, decodeOptions{}).Items
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	item := items[0]
+	checks := []struct {
+		field, got, want string
+	}{
+		{"title", item.Title, "Episode 1"},
+		{"link", item.Link, "https://example.com/1"},
+		{"guid", item.GUID.Value, "ep-1"},
+		{"guid isPermaLink", item.GUID.IsPermaLink, "false"},
+		{"pubDate", item.PubDate, "Mon, 12 Oct 2026 10:00:00 +0000"},
+		{"author", item.Author, ""},
+		{"creators", strings.Join(item.Creators, ","), "Alice,Bob"},
+		{"categories", strings.Join(item.Categories, ","), "go,data"},
+		{"description", item.Description, "Summary"},
+		{"content", item.Content, "<p>Body</p>"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+
+	extras := make(map[string]string)
+	for _, extra := range item.Extra {
+		extras[extra.Name.Space+" "+extra.Name.Local] = extra.Inner
+	}
+	for name, want := range map[string]string{
+		itunesNamespace + " title":  "Ep. 1",
+		itunesNamespace + " author": "Jane Doe",
+		" enclosure":                "",
+	} {
+		if got, ok := extras[name]; !ok || got != want {
+			t.Errorf("Extra[%q] = %q (present: %v), want %q", name, got, ok, want)
+		}
+	}
+}
+
+func TestDecodeFeedRSS1(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+  <channel rdf:about="https://example.com/"><title>RSS 1.0</title></channel>
+  <item rdf:about="https://example.com/a">
+    <title>A</title>
+    <link>https://example.com/a</link>
+  </item>
+</rdf:RDF>`
+	items := decodeTestFeed(t, doc, decodeOptions{}).Items
+	if len(items) != 1 || items[0].Title != "A" || items[0].Link != "https://example.com/a" {
+		t.Errorf("items = %+v, want one item titled A", items)
+	}
+}
+
+func TestDecodeFeedAtom(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="nl">
+  <title>Atom</title>
+  <author><name>Feed Author</name></author>
+  <entry>
+    <title>Entry</title>
+    <id>urn:entry:1</id>
+    <link href="https://example.com/entry"/>
+    <published>2026-10-12T10:00:00Z</published>
+  </entry>
+</feed>`
+	channel := decodeTestFeed(t, doc, decodeOptions{})
+	if channel.Language != "nl" {
+		t.Errorf("language = %q, want nl", channel.Language)
+	}
+	if len(channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(channel.Items))
+	}
+	item := channel.Items[0]
+	if item.Title != "Entry" || item.Link != "https://example.com/entry" || item.GUID.Value != "urn:entry:1" {
+		t.Errorf("item = %+v", item)
+	}
+	if authors := item.authors(); len(authors) != 1 || authors[0] != "Feed Author" {
+		t.Errorf("authors = %q, want the feed's author", authors)
+	}
+}
+
+func TestDecodeFeedMaxItems(t *testing.T) {
+	doc := `<rss><channel>
+<item><title>1</title></item><item><title>2</title></item><item><title>3</title></item>
+</channel></rss>`
+	items := decodeTestFeed(t, doc, decodeOptions{maxItems: 2}).Items
+	if len(items) != 2 {
+		t.Errorf("got %d items, want 2", len(items))
+	}
+}
+
+func TestDecodeFeedMaxBytes(t *testing.T) {
+	doc := `<rss><channel><item><title>` + strings.Repeat("x", 1000) + `</title></item></channel></rss>`
+	if _, err := decodeFeed(strings.NewReader(doc), decodeOptions{maxBytes: 100}); err == nil {
+		t.Error("decodeFeed accepted a feed over maxBytes")
+	}
+}
+
+func TestDecodeFeedRecover(t *testing.T) {
+	doc := `<rss><channel>
+<item><title>One <b>bold</title><description>Fish &amp; chips<br>and peas</description></item>
+<item><title>Two</title><description>stray</i> end tag</description></item>
+<item><title>Three &nbsp;</title>
+</channel>`
+	if _, err := decodeFeed(strings.NewReader(doc), decodeOptions{}); err == nil {
+		t.Error("decodeFeed accepted a malformed feed without recover")
+	}
+	items := decodeTestFeed(t, doc, decodeOptions{recover: true}).Items
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, strings.TrimSpace(item.Title))
+	}
+	if got := strings.Join(titles, ","); got != "One,Two,Three" {
+		t.Errorf("titles = %q, want One,Two,Three", got)
+	}
+}
+
+func TestDecodeFeedCutoff(t *testing.T) {
+	doc := `<rss><channel>
+<item><title>new</title><pubDate>Thu, 15 Oct 2026 10:00:00 +0000</pubDate></item>
+<item><title>old 1</title><pubDate>Mon, 05 Oct 2026 10:00:00 +0000</pubDate></item>
+<item><title>undated</title></item>
+<item><title>out of order</title><pubDate>Wed, 14 Oct 2026 10:00:00 +0000</pubDate></item>
+<item><title>old 2</title><pubDate>Sun, 04 Oct 2026 10:00:00 +0000</pubDate></item>
+<item><title>old 3</title><pubDate>Sat, 03 Oct 2026 10:00:00 +0000</pubDate></item>
+<item><title>never read</title><pubDate>Fri, 02 Oct 2026 10:00:00 +0000</pubDate></item>
+</channel></rss>`
+	cutoff := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		opts      decodeOptions
+		wantItems int
+	}{
+		{"no cutoff", decodeOptions{lookahead: 2}, 7},
+		{"no lookahead", decodeOptions{cutoff: cutoff}, 7},
+		// A newer item resets the run; the undated one doesn't count.
+		{"lookahead 2", decodeOptions{cutoff: cutoff, lookahead: 2}, 6},
+		{"lookahead 1", decodeOptions{cutoff: cutoff, lookahead: 1}, 2},
+		{"lookahead 5", decodeOptions{cutoff: cutoff, lookahead: 5}, 7},
+	}
+	for _, tt := range tests {
+		if got := len(decodeTestFeed(t, doc, tt.opts).Items); got != tt.wantItems {
+			t.Errorf("%s: got %d items, want %d", tt.name, got, tt.wantItems)
+		}
+	}
+}
+
+func TestDecodeFeedRepairs(t *testing.T) {
+	doc := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<rss><channel><item>" +
+		"<title>Fish & chips\x01 at caf\xe9</title>" +
+		"<description><![CDATA[a && b]]></description>" +
+		"</item></channel></rss>"
+	if _, err := decodeFeed(strings.NewReader(doc), decodeOptions{}); err == nil {
+		t.Error("decodeFeed accepted a stray ampersand without recover")
+	}
+	items := decodeTestFeed(t, doc, decodeOptions{recover: true}).Items
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	if items[0].Title != "Fish & chips at café" || items[0].Description != "a && b" {
+		t.Errorf("item = %q / %q", items[0].Title, items[0].Description)
+	}
+}
+
+func TestDecodeFeedLimits(t *testing.T) {
+	tests := []struct {
+		name, doc string
+	}{
+		{"custom entity", `<!DOCTYPE rss [<!ENTITY lol "lol">]><rss><channel><title>&lol;</title></channel></rss>`},
+		{"deep nesting", "<rss><channel>" + strings.Repeat("<x>", maxXMLDepth) + strings.Repeat("</x>", maxXMLDepth) + "</channel></rss>"},
+		{"long attribute", `<rss><channel><item><enclosure url="` + strings.Repeat("a", maxXMLAttrLength+1) + `"/></item></channel></rss>`},
+	}
+	for _, tt := range tests {
+		for _, recover := range []bool{false, true} {
+			if _, err := decodeFeed(strings.NewReader(tt.doc), decodeOptions{recover: recover}); err == nil {
+				t.Errorf("%s (recover=%v): decodeFeed accepted the feed", tt.name, recover)
+			}
+		}
+	}
+}
+
+func TestDecodeFeedChannelMetadata(t *testing.T) {
+	doc := `<rss xmlns:atom="http://www.w3.org/2005/Atom" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+<title>Blog</title>
+<atom:link href="https://example.com/feed" rel="self" type="application/rss+xml"/>
+<link>https://example.com/</link>
+<atom:link href="https://example.com/feed?page=2" rel="next"/>
+<description>All posts</description>
+<language>en-GB</language>
+<generator>WordPress</generator>
+<sy:updatePeriod>hourly</sy:updatePeriod>
+<image><url>https://example.com/logo.png</url><title>Blog</title><link>https://example.com/</link></image>
+<item><title>Post</title></item>
+</channel></rss>`
+	channel := decodeTestFeed(t, doc, decodeOptions{})
+	checks := []struct {
+		field, got, want string
+	}{
+		{"title", channel.Title, "Blog"},
+		{"link", channel.Link, "https://example.com/"},
+		{"description", channel.Description, "All posts"},
+		{"language", channel.Language, "en-GB"},
+		{"generator", channel.Generator, "WordPress"},
+		{"self link", channel.selfLink(), "https://example.com/feed"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+	if len(channel.AtomLinks) != 2 {
+		t.Errorf("atom links = %+v, want 2", channel.AtomLinks)
+	}
+	if channel.Image == nil || channel.Image.URL != "https://example.com/logo.png" {
+		t.Errorf("image = %+v", channel.Image)
+	}
+	if len(channel.Items) != 1 {
+		t.Errorf("got %d items, want 1", len(channel.Items))
+	}
+
+	var out strings.Builder
+	outputRSS(&out, channel.Items, channel, provenance{})
+	for _, want := range []string{"<language>en-GB</language>", "<url>https://example.com/logo.png</url>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %s:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestFollowPagination(t *testing.T) {
+	pages := map[string]string{
+		"/feed": `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+<atom:link rel="next" href="/feed?page=2"/>
+<item><title>1</title><pubDate>Thu, 15 Oct 2026 10:00:00 +0000</pubDate></item>
+<item><title>2</title><pubDate>Wed, 14 Oct 2026 10:00:00 +0000</pubDate></item>
+</channel></rss>`,
+		"/feed?page=2": `<feed xmlns="http://www.w3.org/2005/Atom">
+<link rel="next" href="page3.xml"/>
+<entry><title>3</title><updated>2026-10-13T10:00:00Z</updated></entry>
+<entry><title>4</title><updated>2026-10-12T10:00:00Z</updated></entry>
+</feed>`,
+		"/page3.xml": `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+<atom:link rel="next" href="/feed"/>
+<item><title>5</title><pubDate>Sun, 11 Oct 2026 10:00:00 +0000</pubDate></item>
+</channel></rss>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		maxPages  int
+		opts      decodeOptions
+		wantItems int
+	}{
+		{"all pages, stopping at the loop", 10, decodeOptions{}, 5},
+		{"max pages", 2, decodeOptions{}, 4},
+		{"max items", 10, decodeOptions{maxItems: 3}, 3},
+		{"cutoff", 10, decodeOptions{cutoff: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := decodeTestFeed(t, pages["/feed"], tt.opts)
+			if err := followPagination(&first, server.URL+"/feed", tt.maxPages, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if len(first.Items) != tt.wantItems {
+				t.Errorf("got %d items, want %d", len(first.Items), tt.wantItems)
+			}
+		})
+	}
+
+	broken := Channel{AtomLinks: []AtomLink{{Rel: "next", Href: "/missing"}}}
+	if err := followPagination(&broken, server.URL+"/feed", 10, decodeOptions{}); err == nil {
+		t.Error("followPagination ignored a missing page")
+	}
+}
+
+func TestDecodeFeedSizeLimit(t *testing.T) {
+	doc := `<rss><channel><item><title>` + strings.Repeat("x", 1000) + `</title></item></channel></rss>`
+	tests := []struct {
+		maxBytes int64
+		wantErr  bool
+	}{
+		{0, false},
+		{int64(len(doc)), false},
+		{int64(len(doc)) - 1, true},
+		{10, true},
+	}
+	for _, tt := range tests {
+		_, err := decodeFeed(strings.NewReader(doc), decodeOptions{maxBytes: tt.maxBytes})
+		if tt.wantErr && !errors.Is(err, errFeedTooLarge) {
+			t.Errorf("maxBytes %d: err = %v, want errFeedTooLarge", tt.maxBytes, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("maxBytes %d: %v", tt.maxBytes, err)
+		}
+	}
+}
+
+func TestDecodeFeedMaxItemsStopsReading(t *testing.T) {
+	// The document is cut off after the second item; decoding stops before
+	// reaching the truncation.
+	doc := `<rss><channel><item><title>1</title></item><item><title>2</title></item><item><title>`
+	items := decodeTestFeed(t, doc, decodeOptions{maxItems: 2}).Items
+	if len(items) != 2 {
+		t.Errorf("got %d items, want 2", len(items))
+	}
+}
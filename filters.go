@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// itemFilter is one rule of the filtering pipeline. Items for which keep
+// returns false are dropped, and the drop is attributed to name.
+type itemFilter struct {
+	name string
+	keep func(Item) bool
+
+	// rule describes the filter's settings, for rulesHash.
+	rule string
+}
+
+// filterFlags holds the command-line options that select filters, so the
+// main command and the analysis subcommands apply exactly the same rules.
+type filterFlags struct {
+	authors     bool
+	authorMatch string
+	authorsFile string
+	authorsURL  string
+	since       string
+
+	requireEnclosure string
+	excludeEnclosure string
+
+	scoreConfig string
+	minScore    string
+	scoring     *scorer
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.authors, "authors", false, "Enable author filtering using ALLOWED_AUTHOR_LIST environment variable")
+	fs.StringVar(&f.authorsFile, "authors-file", "", "File with allowed authors, one per line (implies --authors)")
+	fs.StringVar(&f.authorsURL, "authors-url", "", "URL of an allowed-author list, cached locally (implies --authors)")
+	fs.StringVar(&f.authorMatch, "author-match", "any", "With --authors, keep co-authored items when 'any' or 'all' of their authors are allowed")
+	fs.StringVar(&f.requireEnclosure, "require-enclosure", "", "Keep only items with an enclosure matching one of these comma-separated MIME types (e.g. 'audio/*')")
+	fs.StringVar(&f.excludeEnclosure, "exclude-enclosure", "", "Drop items with an enclosure matching one of these comma-separated MIME types (e.g. 'audio/*')")
+	fs.StringVar(&f.scoreConfig, "score-config", "", "JSON file with keyword weights used by --min-score and --sort score")
+	fs.StringVar(&f.minScore, "min-score", "", "Drop items scoring below this value (requires --score-config)")
+	fs.StringVar(&f.since, "since", "0", "How far to look back: days (7), a duration (36h, 90m), or 'last-run' (0 = no limit)")
+}
+
+// buildFilters returns the configured filters in the order they are
+// applied. cutoff is the resolved --since value; a zero time disables the
+// date filter.
+func (f *filterFlags) buildFilters(cutoff time.Time) ([]itemFilter, error) {
+	if f.authorMatch != "any" && f.authorMatch != "all" {
+		return nil, errors.New("--author-match must be 'any' or 'all'")
+	}
+
+	var filters []itemFilter
+	if f.authors || f.authorsFile != "" || f.authorsURL != "" {
+		allowedAuthors, err := f.loadAllowedAuthors()
+		if err != nil {
+			return nil, err
+		}
+		requireAll := f.authorMatch == "all"
+		names := make([]string, 0, len(allowedAuthors))
+		for name := range allowedAuthors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		filters = append(filters, itemFilter{
+			name: "authors",
+			keep: func(item Item) bool {
+				return authorsAllowed(item.authors(), allowedAuthors, requireAll)
+			},
+			rule: f.authorMatch + ": " + strings.Join(names, ", "),
+		})
+	}
+	if f.requireEnclosure != "" {
+		patterns, err := parseMIMEPatterns(f.requireEnclosure)
+		if err != nil {
+			return nil, fmt.Errorf("--require-enclosure: %w", err)
+		}
+		filters = append(filters, itemFilter{
+			name: "require-enclosure",
+			keep: func(item Item) bool {
+				return hasMatchingEnclosure(item, patterns)
+			},
+			rule: strings.Join(patterns, ","),
+		})
+	}
+	if f.excludeEnclosure != "" {
+		patterns, err := parseMIMEPatterns(f.excludeEnclosure)
+		if err != nil {
+			return nil, fmt.Errorf("--exclude-enclosure: %w", err)
+		}
+		filters = append(filters, itemFilter{
+			name: "exclude-enclosure",
+			keep: func(item Item) bool {
+				return !hasMatchingEnclosure(item, patterns)
+			},
+			rule: strings.Join(patterns, ","),
+		})
+	}
+	if f.minScore != "" {
+		minScore, err := strconv.ParseFloat(f.minScore, 64)
+		if err != nil {
+			return nil, fmt.Errorf("--min-score must be a number: %w", err)
+		}
+		s, err := f.scorer()
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, errors.New("--min-score requires --score-config")
+		}
+		filters = append(filters, itemFilter{
+			name: "min-score",
+			keep: func(item Item) bool {
+				return s.score(item) >= minScore
+			},
+			rule: fmt.Sprintf("%v: %s", minScore, s.describe()),
+		})
+	}
+	if !cutoff.IsZero() {
+		filters = append(filters, itemFilter{
+			name: "since",
+			keep: func(item Item) bool {
+				pubDate, err := parseRSSDate(item.PubDate)
+				return err == nil && !pubDate.Before(cutoff)
+			},
+			// The --since setting rather than the cutoff, which moves
+			// with every run.
+			rule: f.since,
+		})
+	}
+	return filters, nil
+}
+
+// scorer returns the scorer from --score-config, loading it on first use,
+// or nil when no config was given.
+func (f *filterFlags) scorer() (*scorer, error) {
+	if f.scoring == nil && f.scoreConfig != "" {
+		s, err := loadScorer(f.scoreConfig)
+		if err != nil {
+			return nil, err
+		}
+		f.scoring = s
+	}
+	return f.scoring, nil
+}
+
+// parseMIMEPatterns splits a comma-separated list of MIME type globs such
+// as "audio/*,video/mp4".
+func parseMIMEPatterns(spec string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid MIME pattern %q", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return nil, errors.New("no MIME types given")
+	}
+	return patterns, nil
+}
+
+func hasMatchingEnclosure(item Item, patterns []string) bool {
+	for _, mimeType := range item.enclosureTypes() {
+		// Ignore parameters such as "; codecs=...".
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		mimeType = strings.TrimSpace(mimeType)
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, mimeType); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyFilters returns the items every filter keeps, and how many items
+// each filter dropped. An item is attributed to the first filter that drops
+// it.
+func applyFilters(items []Item, filters []itemFilter) ([]Item, map[string]int) {
+	var kept []Item
+	dropped := make(map[string]int)
+	for _, item := range items {
+		if name := firstRejectingFilter(item, filters); name != "" {
+			dropped[name]++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept, dropped
+}
+
+func firstRejectingFilter(item Item, filters []itemFilter) string {
+	for _, filter := range filters {
+		if !filter.keep(item) {
+			return filter.name
+		}
+	}
+	return ""
+}
+
+func describeDrops(filters []itemFilter, dropped map[string]int) string {
+	if len(filters) == 0 {
+		return "no filters"
+	}
+	description := ""
+	for i, filter := range filters {
+		if i > 0 {
+			description += ", "
+		}
+		description += fmt.Sprintf("%d dropped by %s", dropped[filter.name], filter.name)
+	}
+	return description
+}
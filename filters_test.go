@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func enclosure(mimeType string) RawElement {
+	return RawElement{
+		Name:  xml.Name{Local: "enclosure"},
+		Attrs: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: "https://example.com/file"}, {Name: xml.Name{Local: "type"}, Value: mimeType}},
+	}
+}
+
+func TestParseMIMEPatterns(t *testing.T) {
+	patterns, err := parseMIMEPatterns(" Audio/*, ,video/mp4 ")
+	if err != nil || len(patterns) != 2 || patterns[0] != "audio/*" || patterns[1] != "video/mp4" {
+		t.Errorf("parseMIMEPatterns = %q, %v", patterns, err)
+	}
+	for _, bad := range []string{"", " , ", "audio/[mp"} {
+		if _, err := parseMIMEPatterns(bad); err == nil {
+			t.Errorf("parseMIMEPatterns(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestEnclosureFilters(t *testing.T) {
+	items := map[string]Item{
+		"podcast":    {Title: "podcast", Extra: []RawElement{enclosure("audio/mpeg")}},
+		"video":      {Title: "video", Extra: []RawElement{enclosure("Video/MP4; codecs=avc1")}},
+		"image":      {Title: "image", Extra: []RawElement{enclosure("image/png")}},
+		"plain":      {Title: "plain"},
+		"namespaced": {Title: "namespaced", Extra: []RawElement{{Name: xml.Name{Space: mediaNamespace, Local: "enclosure"}, Attrs: []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "audio/mpeg"}}}}},
+	}
+	tests := []struct {
+		name  string
+		flags filterFlags
+		kept  map[string]bool
+	}{
+		{
+			name:  "require",
+			flags: filterFlags{authorMatch: "any", requireEnclosure: "audio/*,video/mp4"},
+			kept:  map[string]bool{"podcast": true, "video": true},
+		},
+		{
+			name:  "exclude",
+			flags: filterFlags{authorMatch: "any", excludeEnclosure: "image/*"},
+			kept:  map[string]bool{"podcast": true, "video": true, "plain": true, "namespaced": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := tt.flags.buildFilters(time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, item := range items {
+				kept := firstRejectingFilter(item, filters) == ""
+				if kept != tt.kept[name] {
+					t.Errorf("%s kept = %v, want %v", name, kept, tt.kept[name])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	cutoff := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	filters, err := (&filterFlags{authorMatch: "any", since: "7", requireEnclosure: "audio/*"}).buildFilters(cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []Item{
+		{Title: "kept", PubDate: "Mon, 12 Oct 2026 10:00:00 +0000", Extra: []RawElement{enclosure("audio/mpeg")}},
+		{Title: "old", PubDate: "Mon, 05 Oct 2026 10:00:00 +0000", Extra: []RawElement{enclosure("audio/mpeg")}},
+		{Title: "undated", Extra: []RawElement{enclosure("audio/mpeg")}},
+		{Title: "old and silent", PubDate: "Mon, 05 Oct 2026 10:00:00 +0000"},
+	}
+	kept, dropped := applyFilters(items, filters)
+	if len(kept) != 1 || kept[0].Title != "kept" {
+		t.Errorf("kept = %+v, want only the recent podcast", kept)
+	}
+	// Drops are attributed to the first filter that rejects an item.
+	if dropped["require-enclosure"] != 1 || dropped["since"] != 2 {
+		t.Errorf("dropped = %v", dropped)
+	}
+	if got, want := describeDrops(filters, dropped), "1 dropped by require-enclosure, 2 dropped by since"; got != want {
+		t.Errorf("describeDrops = %q, want %q", got, want)
+	}
+	if got := describeDrops(nil, nil); got != "no filters" {
+		t.Errorf("describeDrops without filters = %q", got)
+	}
+}
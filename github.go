@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// actionsReport summarizes a run for GitHub Actions (--github-actions).
+type actionsReport struct {
+	feed     string
+	itemsIn  int
+	itemsOut int
+	filters  []itemFilter
+	dropped  map[string]int
+	newItems int
+	// newItemsUnknown leaves new_item_count out, for runs that can't tell
+	// which items are new.
+	newItemsUnknown bool
+	feedPath        string
+	duration        time.Duration
+}
+
+// writeActionsReport appends a Markdown summary to $GITHUB_STEP_SUMMARY and
+// sets the new_item_count and feed_path step outputs in $GITHUB_OUTPUT.
+// Either file is skipped when its variable isn't set.
+func writeActionsReport(report actionsReport) error {
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		if err := appendToFile(path, report.summary()); err != nil {
+			return fmt.Errorf("failed to write step summary: %w", err)
+		}
+	}
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		outputs := fmt.Sprintf("feed_path=%s\n", report.feedPath)
+		if !report.newItemsUnknown {
+			outputs = fmt.Sprintf("new_item_count=%d\n", report.newItems) + outputs
+		}
+		if err := appendToFile(path, outputs); err != nil {
+			return fmt.Errorf("failed to write step outputs: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r actionsReport) summary() string {
+	var sb strings.Builder
+	sb.WriteString("### Filtered feed\n\n")
+	sb.WriteString("| | |\n|---|---|\n")
+	if r.feed != "" {
+		fmt.Fprintf(&sb, "| Source | %s |\n", markdownTableCell(r.feed))
+	}
+	fmt.Fprintf(&sb, "| Items read | %d |\n", r.itemsIn)
+	for _, filter := range r.filters {
+		fmt.Fprintf(&sb, "| Dropped by %s | %d |\n", filter.name, r.dropped[filter.name])
+	}
+	fmt.Fprintf(&sb, "| Items written | %d |\n", r.itemsOut)
+	if !r.newItemsUnknown {
+		fmt.Fprintf(&sb, "| New items | %d |\n", r.newItems)
+	}
+	if r.feedPath != "" {
+		fmt.Fprintf(&sb, "| Output | `%s` |\n", markdownTableCell(r.feedPath))
+	}
+	fmt.Fprintf(&sb, "| Duration | %s |\n\n", r.duration.Round(time.Millisecond))
+	return sb.String()
+}
+
+func markdownTableCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+func appendToFile(path, content string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func reportToActions(report actionsReport) {
+	if err := writeActionsReport(report); err != nil {
+		warnf("%v", err)
+	}
+}
+
+// previousOutputItems reads the items of the RSS feed a run is about to
+// replace at path, so they can be compared with the new output. ok is false
+// when that isn't possible; a missing file means all items are new.
+func previousOutputItems(path string) (items []Item, ok bool) {
+	if path == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, true
+	}
+	if err != nil || !bytes.Contains(data, []byte("<rss")) {
+		return nil, false
+	}
+	previous, err := decodeFeed(bytes.NewReader(data), decodeOptions{})
+	if err != nil {
+		return nil, false
+	}
+	synthesizeGUIDs(previous.Items)
+	return previous.Items, true
+}
+
+// countNewItems returns how many of the output items weren't in the
+// --merge-existing feed yet.
+func countNewItems(fresh, existing []Item) int {
+	known := make(map[string]bool, len(existing))
+	for _, item := range existing {
+		known[itemKey(item)] = true
+	}
+	count := 0
+	for _, item := range fresh {
+		if !known[itemKey(item)] {
+			count++
+		}
+	}
+	return count
+}
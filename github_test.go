@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteActionsReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		report      actionsReport
+		wantOutputs string
+	}{
+		{
+			name:        "new items known",
+			report:      actionsReport{itemsIn: 5, itemsOut: 3, newItems: 2, feedPath: "feed.xml", duration: time.Second},
+			wantOutputs: "new_item_count=2\nfeed_path=feed.xml\n",
+		},
+		{
+			name:        "new items unknown",
+			report:      actionsReport{itemsIn: 5, itemsOut: 5, newItemsUnknown: true, duration: time.Second},
+			wantOutputs: "feed_path=\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			outputs := filepath.Join(dir, "outputs")
+			summary := filepath.Join(dir, "summary")
+			t.Setenv("GITHUB_OUTPUT", outputs)
+			t.Setenv("GITHUB_STEP_SUMMARY", summary)
+			if err := writeActionsReport(tt.report); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(outputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.wantOutputs {
+				t.Errorf("outputs = %q, want %q", data, tt.wantOutputs)
+			}
+			data, err = os.ReadFile(summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(data), "| New items |"); got == tt.report.newItemsUnknown {
+				t.Errorf("summary has a New items row: %v, want %v\n%s", got, !got, data)
+			}
+		})
+	}
+}
+
+func TestPreviousOutputItems(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "feed.xml")
+
+	if items, ok := previousOutputItems(path); !ok || len(items) != 0 {
+		t.Errorf("missing feed: items %v, ok %v; want none, true", items, ok)
+	}
+	if _, ok := previousOutputItems(""); ok {
+		t.Error("no --output: ok is true")
+	}
+
+	doc := `<rss><channel><item><title>A</title><link>https://example.com/a</link></item></channel></rss>`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous, ok := previousOutputItems(path)
+	if !ok || len(previous) != 1 {
+		t.Fatalf("items %v, ok %v; want one item", previous, ok)
+	}
+	items := []Item{{Title: "A", Link: "https://example.com/a"}, {Title: "B", Link: "https://example.com/b"}}
+	synthesizeGUIDs(items)
+	if n := countNewItems(items, previous); n != 1 {
+		t.Errorf("countNewItems = %d, want 1", n)
+	}
+
+	if err := os.WriteFile(path, []byte("# Markdown"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := previousOutputItems(path); ok {
+		t.Error("non-RSS output: ok is true")
+	}
+}
+
+func TestCountNewItems(t *testing.T) {
+	existing := []Item{
+		{GUID: GUID{Value: "id-1"}, Link: "https://example.com/1"},
+		{Link: "https://example.com/2"},
+	}
+	fresh := []Item{
+		{GUID: GUID{Value: "id-1"}, Link: "https://example.com/1"},
+		{Link: "https://example.com/2"},
+		{GUID: GUID{Value: "id-3"}, Link: "https://example.com/3"},
+		{Link: "https://example.com/4"},
+	}
+	if got := countNewItems(fresh, existing); got != 2 {
+		t.Errorf("countNewItems = %d, want 2", got)
+	}
+	if got := countNewItems(fresh, nil); got != 4 {
+		t.Errorf("countNewItems without existing items = %d, want 4", got)
+	}
+}
+
+func TestEscapeWorkflowCommand(t *testing.T) {
+	if got, want := escapeWorkflowCommand("50% done\r\nnext"), "50%25 done%0D%0Anext"; got != want {
+		t.Errorf("escapeWorkflowCommand = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+// highlighter finds watch keywords in items so the Markdown digest can bold
+// them and list which ones matched.
+type highlighter struct {
+	keywords []string
+	terms    []*keywordMatcher
+	any      *keywordMatcher
+}
+
+func newHighlighter(spec string) *highlighter {
+	h := &highlighter{}
+	for _, keyword := range strings.Split(spec, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			h.keywords = append(h.keywords, keyword)
+		}
+	}
+	if len(h.keywords) == 0 {
+		return nil
+	}
+	h.any = newKeywordMatcher(h.keywords...)
+	for _, keyword := range h.keywords {
+		h.terms = append(h.terms, newKeywordMatcher(keyword))
+	}
+	return h
+}
+
+// bold wraps every keyword occurrence in text in Markdown bold markers,
+// keeping the text's original case.
+func (h *highlighter) bold(text string) string {
+	if h == nil {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range h.any.find(text) {
+		b.WriteString(text[last:match[0]])
+		b.WriteString("**" + text[match[0]:match[1]] + "**")
+		last = match[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// matched returns the keywords, as configured, that occur in the item's
+// title, categories, description or content.
+func (h *highlighter) matched(item Item) []string {
+	if h == nil {
+		return nil
+	}
+	text := strings.Join([]string{
+		item.Title,
+		strings.Join(item.Categories, "\n"),
+		html.UnescapeString(htmlTagPattern.ReplaceAllString(item.Description+"\n"+item.Content, " ")),
+	}, "\n")
+
+	var matched []string
+	for i, term := range h.terms {
+		if term.match(text) {
+			matched = append(matched, h.keywords[i])
+		}
+	}
+	return matched
+}
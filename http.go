@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const userAgent = "filtered-data-rss (+https://github.com/godatadriven/filtered_data_rss)"
+
+// httpClient is shared by every request the tool makes so connections to the
+// same hosts are pooled and kept alive across the feed fetch and enrichment.
+var httpClient = newHTTPClient(httpOptions{timeout: 30 * time.Second})
+
+type httpOptions struct {
+	timeout time.Duration
+
+	// blockPrivate refuses connections to loopback, private, link-local
+	// and other non-public addresses. The check runs on every dial, after
+	// DNS resolution, so redirects and DNS rebinding are covered too.
+	blockPrivate bool
+
+	// bearerToken is sent as an Authorization header, but only to the hosts
+	// in bearerHosts, so it never leaks to article pages during enrichment.
+	bearerToken string
+	bearerHosts map[string]bool
+
+	// recordDir, when set, receives a copy of every response (--record).
+	// replayDir answers requests from such a copy instead of the network
+	// (--replay).
+	recordDir string
+	replayDir string
+}
+
+func newHTTPClient(opts httpOptions) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	proxy := http.ProxyFromEnvironment
+	if opts.blockPrivate {
+		dialer.Control = rejectNonPublicAddress
+		// A proxy would make every dial go to the proxy's address and
+		// hide the real destination from the check.
+		proxy = nil
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: opts.timeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	var roundTripper http.RoundTripper = transport
+	switch {
+	case opts.replayDir != "":
+		roundTripper = &replayTransport{dir: opts.replayDir}
+	case opts.recordDir != "":
+		roundTripper = &recordingTransport{base: transport, dir: opts.recordDir}
+	}
+	if opts.bearerToken != "" {
+		roundTripper = &bearerTransport{base: roundTripper, token: opts.bearerToken, hosts: opts.bearerHosts}
+	}
+	return &http.Client{Transport: roundTripper, Timeout: opts.timeout}
+}
+
+type bearerTransport struct {
+	base  http.RoundTripper
+	token string
+	hosts map[string]bool
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.hosts[req.URL.Host] && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// urlHost returns the host (with port, if any) of rawURL, or "" for
+// anything that isn't an absolute URL.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func rejectNonPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+	if !isPublicAddress(addr) {
+		return fmt.Errorf("refusing to connect to non-public address %s", addr)
+	}
+	return nil
+}
+
+func isPublicAddress(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func httpGet(rawURL string) (*http.Response, error) {
+	return httpGetWithHeader(rawURL, nil)
+}
+
+// httpGetWithHeader is httpGet with extra request headers, such as
+// If-None-Match.
+func httpGetWithHeader(rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", userAgent)
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		debugf("GET %s failed after %s: %v", rawURL, time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+	debugf("GET %s: %d in %s", rawURL, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	return resp, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestRejectNonPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.216.34:443", false},
+		{"127.0.0.1:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"[::ffff:169.254.169.254]:80", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", false},
+	}
+	for _, tt := range tests {
+		if err := rejectNonPublicAddress("tcp", tt.address, nil); (err != nil) != tt.wantErr {
+			t.Errorf("rejectNonPublicAddress(%s) = %v, want error: %v", tt.address, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBlockPrivateClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	open := newHTTPClient(httpOptions{timeout: 5 * time.Second})
+	resp, err := open.Get(server.URL)
+	if err != nil {
+		t.Fatalf("client without blockPrivate: %v", err)
+	}
+	resp.Body.Close()
+
+	blocking := newHTTPClient(httpOptions{timeout: 5 * time.Second, blockPrivate: true})
+	if resp, err := blocking.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("client with blockPrivate connected to a loopback server")
+	} else if !strings.Contains(err.Error(), "non-public address") {
+		t.Errorf("error = %v, want a non-public address error", err)
+	}
+}
+
+func TestBearerTransport(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client := newHTTPClient(httpOptions{
+		timeout:     5 * time.Second,
+		bearerToken: "token",
+		bearerHosts: map[string]bool{urlHost(server.URL): true},
+	})
+	// The same server under another host name stands in for an article page.
+	other := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	for _, u := range []string{server.URL, other} {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(got) != 2 || got[0] != "Bearer token" || got[1] != "" {
+		t.Errorf("Authorization headers = %q, want the token for the API host only", got)
+	}
+}
+
+func TestHTTPGetReusesConnections(t *testing.T) {
+	var agents, remotes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		remotes = append(remotes, r.RemoteAddr)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, err := httpGet(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	for i, agent := range agents {
+		if agent != userAgent {
+			t.Errorf("request %d User-Agent = %q", i, agent)
+		}
+		if remotes[i] != remotes[0] {
+			t.Errorf("request %d came from %s, not over the first connection from %s", i, remotes[i], remotes[0])
+		}
+	}
+}
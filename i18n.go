@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// translations maps the English strings of rendered output (Markdown items
+// and digests) to another language. Keys are the English text, including
+// fmt verbs and, for dates, Go time layouts such as "Jan 2".
+type translations map[string]string
+
+// get returns the translation of msg, or msg itself if there is none.
+func (t translations) get(msg string) string {
+	if translated, ok := t[msg]; ok && translated != "" {
+		return translated
+	}
+	return msg
+}
+
+// builtinTranslations are used for --lang unless a --translations file
+// overrides them.
+var builtinTranslations = map[string]translations{
+	"nl": {
+		"Unknown":        "Onbekend",
+		"with %s":        "met %s",
+		"matched: %s":    "gevonden: %s",
+		"Undated":        "Zonder datum",
+		"Jan 2":          "2 Jan",
+		"just now":       "zojuist",
+		"1 minute ago":   "1 minuut geleden",
+		"%d minutes ago": "%d minuten geleden",
+		"1 hour ago":     "1 uur geleden",
+		"%d hours ago":   "%d uur geleden",
+		"yesterday":      "gisteren",
+		"%d days ago":    "%d dagen geleden",
+		"last week":      "vorige week",
+		"%d weeks ago":   "%d weken geleden",
+		"last month":     "vorige maand",
+		"%d months ago":  "%d maanden geleden",
+		"last year":      "vorig jaar",
+		"%d years ago":   "%d jaar geleden",
+	},
+}
+
+// loadTranslations returns the strings for lang: the built-in ones, with
+// those in the --translations file at path, if given, taking precedence.
+// The file maps languages to translations:
+//
+//	{"nl": {"Unknown": "Onbekend", "last week": "vorige week"}}
+//
+// English needs no translations, but the file can still reword it.
+func loadTranslations(path, lang string) (translations, error) {
+	merged := translations{}
+	for msg, translated := range builtinTranslations[lang] {
+		merged[msg] = translated
+	}
+	known := lang == "en" || builtinTranslations[lang] != nil
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file map[string]translations
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if messages, ok := file[lang]; ok {
+			known = true
+			for msg, translated := range messages {
+				merged[msg] = translated
+			}
+		}
+	}
+	if !known {
+		languages := []string{"en"}
+		for language := range builtinTranslations {
+			languages = append(languages, language)
+		}
+		sort.Strings(languages)
+		return nil, fmt.Errorf("no translations for %q (built in: %s; add others with --translations)", lang, strings.Join(languages, ", "))
+	}
+	return merged, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTranslations(t *testing.T) {
+	file := writeTestFile(t, "translations.json", `{
+  "nl": {"Unknown": "Anoniem"},
+  "de": {"Unknown": "Unbekannt", "with %s": "mit %s"},
+  "en": {"Unknown": "Anonymous"}
+}`)
+	tests := []struct {
+		path, lang    string
+		unknown, with string
+	}{
+		{"", "en", "Unknown", "with %s"},
+		{"", "nl", "Onbekend", "met %s"},
+		{file, "nl", "Anoniem", "met %s"},
+		{file, "de", "Unbekannt", "mit %s"},
+		{file, "en", "Anonymous", "with %s"},
+	}
+	for _, tt := range tests {
+		tr, err := loadTranslations(tt.path, tt.lang)
+		if err != nil {
+			t.Errorf("loadTranslations(%q, %q): %v", tt.path, tt.lang, err)
+			continue
+		}
+		if got := tr.get("Unknown"); got != tt.unknown {
+			t.Errorf("%s: Unknown = %q, want %q", tt.lang, got, tt.unknown)
+		}
+		if got := tr.get("with %s"); got != tt.with {
+			t.Errorf("%s: with = %q, want %q", tt.lang, got, tt.with)
+		}
+	}
+
+	if _, err := loadTranslations("", "de"); err == nil || !strings.Contains(err.Error(), "en, nl") {
+		t.Errorf("loadTranslations without German translations = %v, want an error listing en, nl", err)
+	}
+	if _, err := loadTranslations(writeTestFile(t, "bad.json", "{"), "nl"); err == nil {
+		t.Error("loadTranslations accepted invalid JSON")
+	}
+}
+
+func TestOutputMarkdownTranslated(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	items := []Item{
+		{Title: "Kubernetes tips", Link: "https://example.com/1", PubDate: "Wed, 14 Oct 2026 12:00:00 +0000"},
+	}
+	var out strings.Builder
+	outputMarkdown(&out, items, markdownOptions{
+		highlight:   newHighlighter("kubernetes"),
+		showMatched: true,
+		dateFormat:  "Monday 2 January",
+		dateLocale:  "nl",
+		relativeAge: true,
+		now:         now,
+		tr:          builtinTranslations["nl"],
+	})
+	want := "- [**Kubernetes** tips](https://example.com/1) - Onbekend (woensdag 14 oktober, 2 dagen geleden)\n" +
+		"  gevonden: kubernetes\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
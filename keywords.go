@@ -0,0 +1,97 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keywordMatcher finds keywords in text, case-insensitively and as whole
+// words: a match may not continue a word on either side. Word boundaries are
+// only required next to letters and digits, so keywords like "c++" still
+// match, and letters are any Unicode letters, so "café" doesn't match inside
+// "cafés". Both the scorer and the highlighter use it, so a keyword matches
+// the same text in either.
+type keywordMatcher struct {
+	keywords []string
+	pattern  *regexp.Regexp
+}
+
+// newKeywordMatcher matches any of keywords, trying longer keywords first so
+// "data mesh" wins over "data". It returns nil when there are no keywords.
+func newKeywordMatcher(keywords ...string) *keywordMatcher {
+	var trimmed []string
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			trimmed = append(trimmed, keyword)
+		}
+	}
+	if len(trimmed) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), trimmed...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	alternatives := make([]string, len(sorted))
+	for i, keyword := range sorted {
+		alternatives[i] = regexp.QuoteMeta(keyword)
+	}
+	return &keywordMatcher{
+		keywords: trimmed,
+		pattern:  regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|")),
+	}
+}
+
+func (m *keywordMatcher) String() string {
+	return strings.Join(m.keywords, "|")
+}
+
+// find returns the start and end of every whole-word match in text.
+func (m *keywordMatcher) find(text string) [][2]int {
+	var matches [][2]int
+	for offset := 0; offset < len(text); {
+		loc := m.pattern.FindStringIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+		start, end := offset+loc[0], offset+loc[1]
+		if wholeWord(text, start, end) {
+			matches = append(matches, [2]int{start, end})
+			offset = end
+			continue
+		}
+		// Retry one rune further on, in case another keyword matches
+		// inside the rejected one.
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + max(size, 1)
+	}
+	return matches
+}
+
+func (m *keywordMatcher) match(text string) bool {
+	return len(m.find(text)) > 0
+}
+
+// wholeWord reports whether text[start:end] doesn't continue a word before or
+// after it.
+func wholeWord(text string, start, end int) bool {
+	if end == start {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(text[start:])
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	if start > 0 && isWordRune(first) && isWordRune(before) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text[:end])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	if end < len(text) && isWordRune(last) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
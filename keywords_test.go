@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestKeywordMatcher(t *testing.T) {
+	tests := []struct {
+		keyword, text string
+		want          bool
+	}{
+		{"ai", "Using AI in production", true},
+		{"ai", "How to maintain pipelines", false},
+		{"ai", "ai_ops", false},
+		{"c++", "Modern C++ tips", true},
+		{"c++", "c++20 is here", true},
+		{".net", "Moving to .NET", true},
+		{"data mesh", "A data-mesh primer", false},
+		{"data mesh", "A data mesh primer", true},
+		{"café", "Het café is open", true},
+		{"café", "Twee cafés", false},
+		{"spark", "Über Sparkasse", false},
+		{"spark", "Übersicht: Spark", true},
+		{"spark", "ÄSpark", false},
+	}
+	for _, tt := range tests {
+		if got := newKeywordMatcher(tt.keyword).match(tt.text); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.keyword, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordMatcherRetriesInsideRejectedMatch(t *testing.T) {
+	// "datadog" is rejected as part of "datadogs"; "dog" is still found.
+	m := newKeywordMatcher("datadog", "dog")
+	if got := m.find("datadogs dog"); len(got) != 1 || got[0] != [2]int{9, 12} {
+		t.Errorf("find = %v, want [[9 12]]", got)
+	}
+}
+
+func TestScorerAndHighlighterAgree(t *testing.T) {
+	texts := []string{"Über Spark", "ÜberSpark", "Spark_SQL", "spark!"}
+	h := newHighlighter("spark")
+	s := &scorer{terms: []scoreTerm{{matcher: newKeywordMatcher("spark"), weight: 1}}, fields: map[string]float64{"title": 1}}
+	for _, text := range texts {
+		item := Item{Title: text}
+		if highlighted, scored := len(h.matched(item)) > 0, s.score(item) > 0; highlighted != scored {
+			t.Errorf("%q: highlighted %v but scored %v", text, highlighted, scored)
+		}
+	}
+}
+
+func TestHighlighterBold(t *testing.T) {
+	h := newHighlighter("data, data mesh, c++")
+	got := h.bold("Data mesh and C++ for database people")
+	want := "**Data mesh** and **C++** for database people"
+	if got != want {
+		t.Errorf("bold = %q, want %q", got, want)
+	}
+}
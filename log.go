@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	verbosityQuiet = iota
+	verbosityNormal
+	verbosityVerbose
+)
+
+// verbosity controls what goes to stderr besides errors, which are always
+// printed: --quiet hides warnings and progress, --verbose adds detail about
+// every fetch and filter step.
+var verbosity = verbosityNormal
+
+// githubActions turns errors and warnings into workflow command annotations,
+// so they show up on the run's summary page (--github-actions).
+var githubActions bool
+
+// beforeExit, when set, runs before fatalf exits, so a failed run can still
+// leave a record such as its metrics file behind.
+var beforeExit func()
+
+// fatalf prints an error and exits with status 1.
+func fatalf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if githubActions {
+		fmt.Fprintf(os.Stderr, "::error::%s\n", escapeWorkflowCommand(message))
+	} else {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	if hook := beforeExit; hook != nil {
+		beforeExit = nil
+		hook()
+	}
+	os.Exit(1)
+}
+
+func warnf(format string, args ...interface{}) {
+	if verbosity < verbosityNormal {
+		return
+	}
+	if githubActions {
+		fmt.Fprintf(os.Stderr, "::warning::%s\n", escapeWorkflowCommand(fmt.Sprintf(format, args...)))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+func infof(format string, args ...interface{}) {
+	if verbosity >= verbosityNormal {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+func debugf(format string, args ...interface{}) {
+	if verbosity >= verbosityVerbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// escapeWorkflowCommand escapes a message for a GitHub Actions workflow
+// command, where newlines would otherwise end the annotation.
+func escapeWorkflowCommand(message string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(message)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// captureOutput returns what f writes to *file, such as os.Stdout.
+func captureOutput(t *testing.T, file **os.File, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := *file
+	*file = w
+	defer func() { *file = original }()
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+func TestVerbosity(t *testing.T) {
+	defer func(v int, actions bool) { verbosity, githubActions = v, actions }(verbosity, githubActions)
+	logAll := func() {
+		warnf("careful %d", 1)
+		infof("progress %d", 2)
+		debugf("detail %d", 3)
+	}
+	tests := []struct {
+		name      string
+		verbosity int
+		actions   bool
+		want      string
+	}{
+		{"quiet", verbosityQuiet, false, ""},
+		{"normal", verbosityNormal, false, "Warning: careful 1\nprogress 2\n"},
+		{"verbose", verbosityVerbose, false, "Warning: careful 1\nprogress 2\ndetail 3\n"},
+		{"github actions", verbosityNormal, true, "::warning::careful 1\nprogress 2\n"},
+	}
+	for _, tt := range tests {
+		verbosity, githubActions = tt.verbosity, tt.actions
+		if got := captureOutput(t, &os.Stderr, logAll); got != tt.want {
+			t.Errorf("%s: stderr = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/sha256"
 	"encoding/xml"
 	"flag"
@@ -10,7 +11,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,140 +21,882 @@ type RSS struct {
 	Channel Channel `xml:"channel"`
 }
 
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
 type Channel struct {
-	Items []Item `xml:"item"`
+	// AtomLinks comes before Link so atom:link elements don't match the
+	// namespace-less link field.
+	AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	Description string     `xml:"description"`
+	Language    string     `xml:"language"`
+	Generator   string     `xml:"generator"`
+	Image       *Image     `xml:"image"`
+	Items       []Item     `xml:"item"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type Image struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+func (c Channel) selfLink() string {
+	for _, link := range c.AtomLinks {
+		if link.Rel == "self" {
+			return link.Href
+		}
+	}
+	return ""
 }
 
+// Item is an RSS item. Its fields are decoded by UnmarshalXML (feed.go), so
+// that only the RSS elements themselves, not namesakes from other namespaces
+// such as itunes:author, end up in them.
 type Item struct {
-	Title       string   `xml:"title"`
-	Link        string   `xml:"link"`
-	PubDate     string   `xml:"pubDate"`
-	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
-	Description string   `xml:"description"`
-	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
-	GUID        string   `xml:"guid"`
-	Categories  []string `xml:"category"`
+	Title       string
+	Link        string
+	PubDate     string
+	Creators    []string
+	Author      string
+	Description string
+	Content     string
+	GUID        GUID
+	Categories  []string
+
+	// Extra holds every child element not mapped above, so extension
+	// elements from the source feed survive into the output.
+	Extra []RawElement
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "stats":
+			runStats(os.Args[2:])
+			return
+		case "test-rules":
+			runTestRules(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		}
+	}
+
 	feedURL := flag.String("feed", "", "RSS feed URL")
-	sinceDays := flag.Int("since", 0, "Number of days to look back (0 = no limit)")
-	enableAuthors := flag.Bool("authors", false, "Enable author filtering using ALLOWED_AUTHOR_LIST environment variable")
-	format := flag.String("format", "rss", "Output format: 'rss' or 'markdown'")
+	var filterOpts filterFlags
+	filterOpts.register(flag.CommandLine)
+	stateFile := flag.String("state-file", "", "JSON file recording the last successful run, used by --since last-run")
+	outputPath := flag.String("output", "", "Write the output to this file instead of stdout")
+	format := flag.String("format", "rss", "Output format: 'rss', 'markdown', 'parquet' or 'ndjson' (BigQuery-ready, see --bigquery-schema)")
 	saveToDir := flag.String("save-to", "", "Directory to save individual article files")
 	buildFromDir := flag.String("build-from", "", "Directory to read article files from and build combined feed")
+	maxPerAuthor := flag.Int("max-per-author", 0, "Maximum number of items per author in the output, applied after sorting (0 = no limit)")
+	digest := flag.String("digest", "", "Split Markdown output into 'weekly' or 'monthly' sections")
+	digestDir := flag.String("digest-dir", "", "With --digest, write one Markdown file per period into this directory instead of stdout")
+	groupBy := flag.String("group-by", "", "Markdown layout: 'author' groups items under author headings, most prolific first")
+	highlight := flag.String("highlight", "", "Comma-separated keywords to bold in Markdown output")
+	showMatched := flag.Bool("show-matched", false, "In Markdown output, add a 'matched:' line listing the --highlight keywords each item mentions")
+	dateFormat := flag.String("date-format", "", "In Markdown output, add each item's date in this Go time layout, e.g. '2 January 2006'")
+	dateLocale := flag.String("date-locale", "", "Language of month and weekday names in dates: 'en', 'nl', 'de', 'fr' or 'es' (default: --lang if it's one of these, else 'en')")
+	lang := flag.String("lang", "en", "Language of the fixed strings in Markdown output and digests, e.g. 'nl'")
+	translationsFile := flag.String("translations", "", "JSON file with translations of the Markdown and digest strings per --lang, overriding the built-in ones")
+	relativeAges := flag.Bool("relative-age", false, "In Markdown output, add how long ago each item was published, e.g. '3 days ago'")
+	sortOrder := flag.String("sort", "date-desc", "Output order: 'date-desc', 'date-asc', 'title', 'source' (as read from the feed; newest first with --merge-existing or --build-from), or 'score' (see --score-config)")
+	undated := flag.String("undated", "last", "Where to place items without a parseable date when sorting: 'first' or 'last'")
 	maxItems := flag.Int("max-items", 1000, "Maximum number of items in output feed")
+	limit := flag.Int("limit", 0, "Maximum number of items to emit, applied after sorting and independent of --max-items (0 = no limit)")
+	sourceAPI := flag.String("source-api", "", "Read unread items from an RSS reader account instead of a feed: 'fever' (FreshRSS, Miniflux, ...) or 'feedbin'")
+	markRead := flag.Bool("mark-read", false, "With --source-api, mark the items read after a successful run")
+	mergeExisting := flag.String("merge-existing", "", "URL or file of a previously generated feed to merge new items into")
+	enrich := flag.String("enrich", "", "Comma-separated enrichers to run on each item's page: 'canonical', 'fulltext', 'opengraph'")
+	enrichWorkers := flag.Int("enrich-workers", 8, "Number of items enriched concurrently")
+	enrichPerHost := flag.Int("enrich-per-host", 2, "Maximum concurrent enrichment requests per host")
+	exportSQLitePath := flag.String("export-sqlite", "", "Upsert the emitted items into this SQLite database")
+	bigQuerySchemaPath := flag.String("bigquery-schema", "", "Write the BigQuery schema of --format ndjson rows to this JSON file")
+	bigQueryTable := flag.String("bigquery-table", "", "Append the emitted items to this BigQuery table ('project.dataset.table'), creating it if needed")
+	bigQueryTimeout := flag.Duration("bigquery-timeout", 5*time.Minute, "How long to wait for the --bigquery-table load job to finish")
+	notifyWebhook := flag.String("notify-webhook", "", "POST the items not announced before to this URL as JSON (requires --state-file)")
+	notifySlack := flag.Bool("notify-slack", false, "Announce the items not announced before in Slack, through the incoming webhook in SLACK_WEBHOOK_URL (requires --state-file)")
+	notifyEmail := flag.String("notify-email", "", "Email the items not announced before to these comma-separated addresses, through SMTP_HOST (requires --state-file)")
+	metricsFile := flag.String("metrics-file", "", "Write run metrics to this file: JSON, or the Prometheus text format if it ends in .prom")
+	auditLog := flag.String("audit-log", "", "Append a JSON line for every dropped item, with the rule that dropped it, to this file")
+	ignoreRobots := flag.Bool("ignore-robots", false, "Fetch item links during enrichment even when robots.txt disallows them")
+	followPages := flag.Bool("follow-pagination", false, "Follow rel=\"next\" links of paged and archived feeds and read the items of every page")
+	maxPages := flag.Int("max-pages", 10, "With --follow-pagination, the maximum number of pages to read, including the first")
+	sitemap := flag.Bool("sitemap", false, "Read --feed as a sitemap.xml (or sitemap index) and fetch the listed pages for titles, for sites without a feed")
+	maxSourceItems := flag.Int("max-source-items", 0, "Maximum number of items to read from the source feed (0 = no limit)")
+	defaultTimezone := flag.String("default-timezone", "UTC", "IANA time zone for feed dates that carry no zone, e.g. 'Europe/Amsterdam'")
+	maxFeedBytes := flag.Int64("max-feed-bytes", defaultMaxFeedBytes, "Maximum size of the source feed in bytes")
+	recoverXML := flag.Bool("recover-xml", false, "Try to recover from minor XML errors such as stray ampersands and control characters")
+	blockPrivate := flag.Bool("block-private-addresses", false, "Refuse to fetch URLs that resolve to loopback, private or link-local addresses, including after redirects")
+	mergeStrategySpec := flag.String("merge-strategy", "", "Per-field strategy for duplicate items, e.g. 'description=longest,default=prefer-new'")
+	bearerTokenFile := flag.String("bearer-token-file", "", "File containing a bearer token sent with requests to the --feed and --merge-existing hosts")
+	recordDir := flag.String("record", "", "Save every HTTP response of the run to this directory, for --replay")
+	replayDir := flag.String("replay", "", "Re-run from the HTTP responses saved with --record in this directory, at the recorded time and without network access")
+	httpTimeout := flag.Duration("http-timeout", 30*time.Second, "Timeout for each HTTP request")
+	sinceLookahead := flag.Int("since-lookahead", 5, "Stop reading the feed after this many consecutive items older than --since (0 = read the whole feed)")
+	actionsMode := flag.Bool("github-actions", false, "Report to GitHub Actions: a step summary, new_item_count and feed_path outputs, and error annotations")
+	quiet := flag.Bool("quiet", false, "Only print errors to stderr")
+	verbose := flag.Bool("verbose", false, "Log every fetch, filter decision counts and timing to stderr")
 	flag.Parse()
+	start := time.Now()
+	githubActions = *actionsMode
+
+	if *quiet && *verbose {
+		fatalf("Error: --quiet and --verbose are mutually exclusive")
+	}
+	if *recordDir != "" && *replayDir != "" {
+		fatalf("Error: --record and --replay are mutually exclusive")
+	}
+	if *replayDir != "" {
+		recorded, err := recordedRunTime(*replayDir)
+		if err != nil {
+			fatalf("Error: --replay: %v", err)
+		}
+		start = recorded
+		// A replay only reproduces the output. It announces, exports and
+		// marks nothing, and leaves the state file as it is.
+		*notifyWebhook, *notifySlack, *notifyEmail = "", false, ""
+		*exportSQLitePath, *bigQueryTable, *metricsFile = "", "", ""
+		*markRead = false
+		secretsOffline = true
+		infof("Replaying the run of %s from %s; notifications, exports, metrics and state are skipped", start.Format(time.RFC3339), *replayDir)
+	}
+	if *quiet {
+		verbosity = verbosityQuiet
+	} else if *verbose {
+		verbosity = verbosityVerbose
+	}
+
+	if *format != "rss" && *format != "markdown" && *format != "parquet" && *format != "ndjson" {
+		fmt.Fprintf(os.Stderr, "Error: --format must be 'rss', 'markdown', 'parquet' or 'ndjson'\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *bigQueryTable != "" {
+		if _, _, _, err := parseBigQueryTable(*bigQueryTable); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --bigquery-table: %v\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+	if *bigQueryTimeout <= 0 {
+		fatalf("Error: --bigquery-timeout must be positive")
+	}
+
+	if !validSortOrders[*sortOrder] {
+		fmt.Fprintf(os.Stderr, "Error: --sort must be 'date-desc', 'date-asc', 'title', 'source', or 'score'\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *digest != "" && *digest != "weekly" && *digest != "monthly" {
+		fmt.Fprintf(os.Stderr, "Error: --digest must be 'weekly' or 'monthly'\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *digest != "" && *format != "markdown" {
+		fatalf("Error: --digest requires --format markdown")
+	}
+	if *digestDir != "" && *digest == "" {
+		fatalf("Error: --digest-dir requires --digest")
+	}
+	if *markRead && *sourceAPI == "" {
+		fatalf("Error: --mark-read requires --source-api")
+	}
+	if *followPages && *sourceAPI != "" {
+		fatalf("Error: --follow-pagination can't be combined with --source-api")
+	}
+	if *sitemap && (*sourceAPI != "" || *followPages) {
+		fatalf("Error: --sitemap can't be combined with --source-api or --follow-pagination")
+	}
+	if *maxPages < 1 {
+		fatalf("Error: --max-pages must be at least 1")
+	}
+	if *digestDir != "" && *outputPath != "" {
+		fatalf("Error: --output and --digest-dir are mutually exclusive")
+	}
+
+	if *groupBy != "" && *groupBy != "author" {
+		fmt.Fprintf(os.Stderr, "Error: --group-by must be 'author'\n")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	if *format != "rss" && *format != "markdown" {
-		fmt.Fprintf(os.Stderr, "Error: --format must be 'rss' or 'markdown'\n")
+	tr, err := loadTranslations(*translationsFile, *lang)
+	if err != nil {
+		fatalf("Error: --lang: %v", err)
+	}
+	if *dateLocale == "" {
+		*dateLocale = "en"
+		if _, ok := localeMonths[*lang]; ok {
+			*dateLocale = *lang
+		}
+	}
+	mdOpts := markdownOptions{
+		highlight:   newHighlighter(*highlight),
+		showMatched: *showMatched,
+		groupBy:     *groupBy,
+		newestFirst: *sortOrder != "date-asc",
+		dateFormat:  *dateFormat,
+		dateLocale:  *dateLocale,
+		tr:          tr,
+		relativeAge: *relativeAges,
+		now:         start,
+	}
+	if _, ok := localeMonths[*dateLocale]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: --date-locale must be 'en', 'nl', 'de', 'fr' or 'es'\n")
 		flag.Usage()
 		os.Exit(1)
 	}
+	if *showMatched && mdOpts.highlight == nil {
+		fatalf("Error: --show-matched requires --highlight")
+	}
+
+	scoring, err := filterOpts.scorer()
+	if err != nil {
+		fatalf("Error loading score config: %v", err)
+	}
+	if *sortOrder == "score" && scoring == nil {
+		fatalf("Error: --sort score requires --score-config")
+	}
+
+	if *undated != "first" && *undated != "last" {
+		fmt.Fprintf(os.Stderr, "Error: --undated must be 'first' or 'last'\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	strategy, err := parseMergeStrategy(*mergeStrategySpec)
+	if err != nil {
+		fatalf("Error: --merge-strategy: %v", err)
+	}
+
+	location, err := time.LoadLocation(*defaultTimezone)
+	if err != nil {
+		fatalf("Error: --default-timezone: %v", err)
+	}
+	defaultLocation = location
+
+	metrics := runMetrics{Feed: *feedURL, Timestamp: start, RuleHits: map[string]int{}}
+	if *buildFromDir != "" {
+		metrics.Feed = *buildFromDir
+	}
+	if *metricsFile != "" {
+		// A failed run is recorded too, so monitoring notices a broken
+		// feed instead of a stale metrics file.
+		beforeExit = func() {
+			if metrics.FetchDuration == 0 {
+				metrics.FetchDuration = time.Since(start).Seconds()
+			}
+			if err := writeMetrics(*metricsFile, metrics); err != nil {
+				warnf("%v", err)
+			}
+		}
+		defer func() {
+			beforeExit = nil
+			metrics.Success = true
+			if err := writeMetrics(*metricsFile, metrics); err != nil {
+				fatalf("Error: %v", err)
+			}
+		}()
+	}
 
 	// Mode: build combined feed from article files
 	if *buildFromDir != "" {
 		items, err := loadArticlesFromDir(*buildFromDir)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading articles: %v\n", err)
-			os.Exit(1)
+			fatalf("Error loading articles: %v", err)
 		}
-		sortItemsByDate(items)
+		metrics.FetchDuration = time.Since(start).Seconds()
+		itemsIn := len(items)
+		metrics.ItemsIn = itemsIn
+		synthesizeGUIDs(items)
+		items = mergeAndDeduplicateItems(items, nil, strategy)
+		sortItemsByDate(items, *undated == "first")
 		if len(items) > *maxItems {
 			items = items[:*maxItems]
 		}
-		fmt.Fprintf(os.Stderr, "Built feed from %d articles\n", len(items))
-		if *format == "markdown" {
-			outputMarkdown(items)
-		} else {
-			outputRSS(items)
+		infof("Built feed from %d articles", len(items))
+		sortItems(items, *sortOrder, *undated == "first", scoring)
+		items = capPerAuthor(items, *maxPerAuthor)
+		items = limitItems(items, *limit)
+		metrics.ItemsOut = len(items)
+		// The feed being replaced tells which items are new.
+		var previousItems []Item
+		previousKnown := false
+		if githubActions && *format == "rss" {
+			previousItems, previousKnown = previousOutputItems(*outputPath)
 		}
+		writeOutput(*outputPath, func(w io.Writer) {
+			switch *format {
+			case "markdown":
+				emitMarkdown(w, items, mdOpts, *digest, *digestDir)
+			case "parquet":
+				if err := outputParquet(w, items); err != nil {
+					fatalf("Error writing Parquet: %v", err)
+				}
+			case "ndjson":
+				if err := outputNDJSON(w, items, *buildFromDir, start); err != nil {
+					fatalf("Error writing NDJSON: %v", err)
+				}
+			default:
+				outputRSS(w, items, Channel{}, provenance{generated: start, sources: []string{*buildFromDir}})
+			}
+		})
+		exportItems(*exportSQLitePath, *buildFromDir, items, start)
+		exportBigQuery(*bigQuerySchemaPath, *bigQueryTable, *buildFromDir, items, start, *bigQueryTimeout)
+		if githubActions {
+			report := actionsReport{itemsIn: itemsIn, itemsOut: len(items), feedPath: *outputPath, duration: time.Since(start)}
+			if previousKnown {
+				report.newItems = countNewItems(items, previousItems)
+			} else {
+				report.newItemsUnknown = true
+			}
+			reportToActions(report)
+		}
+		debugf("Finished in %s", time.Since(start).Round(time.Millisecond))
 		return
 	}
 
 	// Mode: fetch, filter, and optionally save articles
-	if *feedURL == "" {
-		fmt.Fprintf(os.Stderr, "Error: --feed or --build-from is required\n")
+	if *feedURL == "" && *sourceAPI == "" {
+		fmt.Fprintf(os.Stderr, "Error: --feed, --source-api or --build-from is required\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	var allowedAuthors map[string]bool
-	if *enableAuthors {
-		allowedAuthorList := os.Getenv("ALLOWED_AUTHOR_LIST")
-		if allowedAuthorList == "" {
-			fmt.Fprintf(os.Stderr, "Error: --authors flag requires ALLOWED_AUTHOR_LIST environment variable to be set\n")
-			os.Exit(1)
-		}
-		allowedAuthors = loadAllowedAuthorsFromEnv(allowedAuthorList)
+	enrichers, err := parseEnrichers(*enrich)
+	if err != nil {
+		fatalf("Error: --enrich: %v", err)
 	}
 
-	resp, err := http.Get(*feedURL)
+	state, err := loadState(*stateFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error fetching feed: %v\n", err)
-		os.Exit(1)
+		fatalf("Error loading state: %v", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "Error: received status code %d\n", resp.StatusCode)
-		os.Exit(1)
+	if filterOpts.since == "last-run" && *stateFile == "" {
+		fatalf("Error: --since last-run requires --state-file")
+	}
+	notifiers, err := newNotifiers(*notifyWebhook, *notifySlack, *notifyEmail)
+	if err != nil {
+		fatalf("Error: %v", err)
+	}
+	if len(notifiers) > 0 && *stateFile == "" {
+		fatalf("Error: --notify-webhook, --notify-slack and --notify-email require --state-file")
+	}
+	cutoffDate, err := parseSince(filterOpts.since, start, state)
+	if err != nil {
+		fatalf("Error: --since: %v", err)
+	}
+	if filterOpts.since == "last-run" && cutoffDate.IsZero() {
+		infof("No previous successful run recorded in %s; not filtering by date", *stateFile)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	bearerToken, err := getenvSecret("FEED_BEARER_TOKEN")
+	if err == nil && *bearerTokenFile != "" {
+		bearerToken, err = readSecretFile(*bearerTokenFile)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading response: %v\n", err)
-		os.Exit(1)
+		fatalf("Error loading bearer token: %v", err)
 	}
 
-	var rss RSS
-	if err := xml.Unmarshal(body, &rss); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing RSS: %v\n", err)
-		os.Exit(1)
+	if *recordDir != "" {
+		if err := startRecording(*recordDir, start, os.Args[1:]); err != nil {
+			fatalf("Error: --record: %v", err)
+		}
 	}
+	httpClient = newHTTPClient(httpOptions{
+		timeout:      *httpTimeout,
+		blockPrivate: *blockPrivate,
+		bearerToken:  bearerToken,
+		bearerHosts:  map[string]bool{urlHost(*feedURL): true, urlHost(*mergeExisting): true},
+		recordDir:    *recordDir,
+		replayDir:    *replayDir,
+	})
 
-	var cutoffDate time.Time
-	if *sinceDays > 0 {
-		cutoffDate = time.Now().AddDate(0, 0, -*sinceDays)
+	filters, err := filterOpts.buildFilters(cutoffDate)
+	if err != nil {
+		fatalf("Error: %v", err)
 	}
 
-	var filteredItems []Item
-	for _, item := range rss.Channel.Items {
-		if allowedAuthors != nil && !allowedAuthors[item.Creator] {
-			continue
+	var robots *robotsCache
+	if !*ignoreRobots {
+		robots = newRobotsCache()
+	}
+
+	var source Channel
+	var service feedService
+	var serviceIDs []string
+	if *sitemap {
+		limit := *maxSourceItems
+		if limit == 0 {
+			limit = defaultSitemapItems
 		}
-		if *sinceDays > 0 {
-			pubDate, err := parseRSSDate(item.PubDate)
-			if err != nil {
-				continue
-			}
-			if pubDate.Before(cutoffDate) {
-				continue
+		source.Items, err = loadSitemap(*feedURL, cutoffDate, limit, *maxFeedBytes)
+		if err != nil {
+			fatalf("Error reading sitemap: %v", err)
+		}
+		// Sitemaps only list links; the titles, descriptions and
+		// thumbnails come from the pages themselves.
+		enrichItems(source.Items, enrichOptions{
+			enrichers: []string{"opengraph"},
+			workers:   *enrichWorkers,
+			perHost:   *enrichPerHost,
+			robots:    robots,
+		})
+		titleSitemapItems(source.Items)
+	} else if *sourceAPI != "" {
+		service, err = newFeedService(*sourceAPI, *feedURL)
+		if err != nil {
+			fatalf("Error: --source-api: %v", err)
+		}
+		source.Items, serviceIDs, err = service.unread(*maxSourceItems)
+		if err != nil {
+			fatalf("Error fetching unread items: %v", err)
+		}
+	} else {
+		resp, err := httpGet(*feedURL)
+		if err != nil {
+			fatalf("Error fetching feed: %v", err)
+		}
+		defer resp.Body.Close()
+		metrics.HTTPStatus = resp.StatusCode
+
+		if resp.StatusCode != http.StatusOK {
+			fatalf("Error: received status code %d", resp.StatusCode)
+		}
+
+		opts := decodeOptions{
+			cutoff:    cutoffDate,
+			lookahead: *sinceLookahead,
+			maxItems:  *maxSourceItems,
+			maxBytes:  *maxFeedBytes,
+			recover:   *recoverXML,
+		}
+		source, err = decodeFeed(resp.Body, opts)
+		if err != nil {
+			fatalf("Error parsing RSS: %v", err)
+		}
+		if *followPages {
+			if err := followPagination(&source, *feedURL, *maxPages, opts); err != nil {
+				fatalf("Error following pagination: %v", err)
 			}
 		}
-		filteredItems = append(filteredItems, item)
+	}
+	metrics.FetchDuration = time.Since(start).Seconds()
+	metrics.ItemsIn = len(source.Items)
+
+	debugf("Decoded %d items from %s", len(source.Items), *feedURL)
+	synthesizeGUIDs(source.Items)
+
+	filteredItems, dropped := applyFilters(source.Items, filters)
+	debugf("Kept %d of %d items (%s)", len(filteredItems), len(source.Items), describeDrops(filters, dropped))
+	metrics.RuleHits = dropped
+
+	if *auditLog != "" {
+		written, err := appendAuditLog(*auditLog, *feedURL, source.Items, filters, start)
+		if err != nil {
+			fatalf("Error writing audit log: %v", err)
+		}
+		debugf("Appended %d dropped items to %s", written, *auditLog)
 	}
 
+	enrichItems(filteredItems, enrichOptions{
+		enrichers: enrichers,
+		workers:   *enrichWorkers,
+		perHost:   *enrichPerHost,
+		robots:    robots,
+	})
+
 	if *saveToDir != "" {
+		metrics.ItemsOut = len(filteredItems)
 		saved, err := saveArticlesToDir(filteredItems, *saveToDir)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving articles: %v\n", err)
-			os.Exit(1)
+			fatalf("Error saving articles: %v", err)
 		}
-		fmt.Fprintf(os.Stderr, "Saved %d new articles to %s\n", saved, *saveToDir)
+		infof("Saved %d new articles to %s", saved, *saveToDir)
+		logEditedItems(filteredItems, state.ContentHashes)
+		exportItems(*exportSQLitePath, *feedURL, filteredItems, start)
+		exportBigQuery(*bigQuerySchemaPath, *bigQueryTable, *feedURL, filteredItems, start, *bigQueryTimeout)
+		if githubActions {
+			reportToActions(actionsReport{
+				feed: *feedURL, itemsIn: len(source.Items), itemsOut: len(filteredItems), filters: filters, dropped: dropped,
+				newItems: saved, duration: time.Since(start),
+			})
+		}
+		notifyNewItems(notifiers, &state, *feedURL, filteredItems, start)
+		markServiceItemsRead(service, serviceIDs, *markRead)
+		if *replayDir == "" {
+			recordSuccessfulRun(*stateFile, state, start, filteredItems)
+		}
+		debugf("Finished in %s", time.Since(start).Round(time.Millisecond))
 		return
 	}
 
-	if *format == "markdown" {
-		outputMarkdown(filteredItems)
-	} else {
-		outputRSS(filteredItems)
+	freshItems := filteredItems
+	previousHashes := state.ContentHashes
+	var existingItems []Item
+	if *mergeExisting != "" {
+		existing, err := openFeed(*mergeExisting)
+		if err != nil {
+			fatalf("Error fetching existing feed: %v", err)
+		}
+		existingFeed, err := decodeFeed(existing, decodeOptions{maxBytes: *maxFeedBytes, recover: *recoverXML})
+		existing.Close()
+		if err != nil {
+			fatalf("Error parsing existing feed: %v", err)
+		}
+		synthesizeGUIDs(existingFeed.Items)
+		existingItems = existingFeed.Items
+		previousHashes = contentHashes(existingItems)
+		fresh := len(filteredItems)
+		filteredItems = mergeAndDeduplicateItems(filteredItems, existingFeed.Items, strategy)
+		debugf("Merged %d new items with %d existing items into %d", fresh, len(existingFeed.Items), len(filteredItems))
+		sortItemsByDate(filteredItems, *undated == "first")
+		if len(filteredItems) > *maxItems {
+			filteredItems = filteredItems[:*maxItems]
+		}
+	}
+	logEditedItems(freshItems, previousHashes)
+
+	sortItems(filteredItems, *sortOrder, *undated == "first", scoring)
+	filteredItems = capPerAuthor(filteredItems, *maxPerAuthor)
+	filteredItems = limitItems(filteredItems, *limit)
+	metrics.ItemsOut = len(filteredItems)
+	writeOutput(*outputPath, func(w io.Writer) {
+		switch *format {
+		case "markdown":
+			emitMarkdown(w, filteredItems, mdOpts, *digest, *digestDir)
+		case "parquet":
+			if err := outputParquet(w, filteredItems); err != nil {
+				fatalf("Error writing Parquet: %v", err)
+			}
+		case "ndjson":
+			if err := outputNDJSON(w, filteredItems, *feedURL, start); err != nil {
+				fatalf("Error writing NDJSON: %v", err)
+			}
+		default:
+			// With --source-api, the service name and its URL, if any.
+			sources := []string{*sourceAPI, *feedURL, *mergeExisting}
+			outputRSS(w, filteredItems, source, provenance{generated: start, sources: sources, filters: filters})
+		}
+	})
+	exportItems(*exportSQLitePath, *feedURL, filteredItems, start)
+	exportBigQuery(*bigQuerySchemaPath, *bigQueryTable, *feedURL, filteredItems, start, *bigQueryTimeout)
+	if githubActions {
+		reportToActions(actionsReport{
+			feed: *feedURL, itemsIn: len(source.Items), itemsOut: len(filteredItems), filters: filters, dropped: dropped,
+			newItems: countNewItems(filteredItems, existingItems), feedPath: *outputPath, duration: time.Since(start),
+		})
+	}
+	notifyNewItems(notifiers, &state, *feedURL, filteredItems, start)
+	markServiceItemsRead(service, serviceIDs, *markRead)
+	if *replayDir == "" {
+		recordSuccessfulRun(*stateFile, state, start, filteredItems)
+	}
+	debugf("Finished in %s", time.Since(start).Round(time.Millisecond))
+}
+
+// capPerAuthor keeps at most max items per author, in the order given, so
+// after sorting each author keeps their first (e.g. newest) items. A
+// co-authored item counts for each of its authors and is dropped once any
+// of them has reached the cap. Items without an author aren't capped.
+func capPerAuthor(items []Item, max int) []Item {
+	if max <= 0 {
+		return items
+	}
+	counts := make(map[string]int)
+	var kept []Item
+	for _, item := range items {
+		authors := item.authors()
+		capped := false
+		for _, author := range authors {
+			if counts[author] >= max {
+				capped = true
+				break
+			}
+		}
+		if capped {
+			continue
+		}
+		for _, author := range authors {
+			counts[author]++
+		}
+		kept = append(kept, item)
+	}
+	if dropped := len(items) - len(kept); dropped > 0 {
+		debugf("Dropped %d items over --max-per-author %d", dropped, max)
+	}
+	return kept
+}
+
+// writeOutput renders the output to stdout, or to outputPath when it's set.
+// The file is replaced atomically, so a feed served from it is never seen
+// half-written.
+func writeOutput(outputPath string, render func(w io.Writer)) {
+	if outputPath == "" {
+		render(os.Stdout)
+		return
+	}
+	var buf bytes.Buffer
+	render(&buf)
+	if err := writeFileAtomic(outputPath, buf.Bytes()); err != nil {
+		fatalf("Error writing output: %v", err)
+	}
+}
+
+// emitMarkdown writes the Markdown output to w, split into digest periods
+// when digest is set, or as one file per period into digestDir.
+func emitMarkdown(w io.Writer, items []Item, opts markdownOptions, digest, digestDir string) {
+	if digest == "" {
+		outputMarkdown(w, items, opts)
+		return
+	}
+	if digestDir == "" {
+		outputDigest(w, items, digest, opts)
+		return
+	}
+	written, err := writeDigestFiles(digestDir, items, digest, opts)
+	if err != nil {
+		fatalf("Error writing digest: %v", err)
+	}
+	infof("Wrote %d digest files to %s", written, digestDir)
+}
+
+func exportItems(sqlitePath, feed string, items []Item, now time.Time) {
+	if sqlitePath == "" {
+		return
+	}
+	if err := exportSQLite(sqlitePath, feed, items, now); err != nil {
+		fatalf("Error exporting to SQLite: %v", err)
+	}
+	debugf("Exported %d items to %s", len(items), sqlitePath)
+}
+
+// markServiceItemsRead marks every item read from the --source-api account
+// as read, kept or not, once the run has succeeded.
+func markServiceItemsRead(service feedService, ids []string, enabled bool) {
+	if !enabled || service == nil || len(ids) == 0 {
+		return
+	}
+	if err := service.markRead(ids); err != nil {
+		warnf("marking items read: %v", err)
+		return
+	}
+	debugf("Marked %d items read", len(ids))
+}
+
+// logEditedItems reports items whose content changed since they were last
+// published, according to previous (see contentHashes).
+func logEditedItems(items []Item, previous map[string]string) {
+	for _, item := range editedItems(items, previous) {
+		infof("Content changed since last published: %s", item.Title)
+	}
+}
+
+func limitItems(items []Item, limit int) []Item {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+func recordSuccessfulRun(stateFile string, state runState, start time.Time, items []Item) {
+	if stateFile == "" {
+		return
+	}
+	state.LastSuccessfulRun = start
+	state.ContentHashes = contentHashes(items)
+	if err := saveState(stateFile, state); err != nil {
+		fatalf("Error saving state: %v", err)
+	}
+}
+
+// authors returns who wrote the item: every dc:creator when present,
+// otherwise the display name from the RSS <author> element.
+func (item Item) authors() []string {
+	var names []string
+	for _, creator := range item.Creators {
+		if creator = strings.TrimSpace(creator); creator != "" {
+			names = append(names, creator)
+		}
+	}
+	if len(names) == 0 {
+		if name := authorDisplayName(item.Author); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// enclosureTypes returns the MIME type of every <enclosure> on the item.
+// Enclosures aren't modelled on Item; they travel with the other extension
+// elements in Extra.
+func (item Item) enclosureTypes() []string {
+	var types []string
+	for _, extra := range item.Extra {
+		if extra.Name.Space != "" || extra.Name.Local != "enclosure" {
+			continue
+		}
+		for _, attr := range extra.Attrs {
+			if attr.Name.Local == "type" {
+				types = append(types, strings.ToLower(strings.TrimSpace(attr.Value)))
+			}
+		}
+	}
+	return types
+}
+
+// image returns the URL of the item's picture: its media:thumbnail, an
+// image media:content, or an image enclosure, in that order of preference.
+func (item Item) image() string {
+	attr := func(e RawElement, name string) string {
+		for _, a := range e.Attrs {
+			if a.Name.Space == "" && a.Name.Local == name {
+				return strings.TrimSpace(a.Value)
+			}
+		}
+		return ""
+	}
+	isImage := func(e RawElement) bool {
+		return attr(e, "medium") == "image" || strings.HasPrefix(strings.ToLower(attr(e, "type")), "image/")
+	}
+	var content, enclosure string
+	for _, extra := range item.Extra {
+		switch {
+		case extra.Name.Space == mediaNamespace && extra.Name.Local == "thumbnail":
+			if url := attr(extra, "url"); url != "" {
+				return url
+			}
+		case extra.Name.Space == mediaNamespace && extra.Name.Local == "content" && isImage(extra):
+			if content == "" {
+				content = attr(extra, "url")
+			}
+		case extra.Name.Space == "" && extra.Name.Local == "enclosure" && isImage(extra):
+			if enclosure == "" {
+				enclosure = attr(extra, "url")
+			}
+		}
+	}
+	if content != "" {
+		return content
 	}
+	return enclosure
+}
+
+// authorsAllowed reports whether any (or, with requireAll, every) author is
+// in the allowed list. Items without authors are never allowed.
+func authorsAllowed(authors []string, allowed map[string]bool, requireAll bool) bool {
+	if len(authors) == 0 {
+		return false
+	}
+	for _, author := range authors {
+		if allowed[author] && !requireAll {
+			return true
+		}
+		if !allowed[author] && requireAll {
+			return false
+		}
+	}
+	return requireAll
+}
+
+// authorDisplayName extracts the name from RSS author values, which are
+// usually "email (Name)" but show up as "Name <email>" or a bare name too.
+func authorDisplayName(author string) string {
+	author = strings.TrimSpace(author)
+	if open := strings.Index(author, "("); open >= 0 && strings.HasSuffix(author, ")") {
+		if name := strings.TrimSpace(author[open+1 : len(author)-1]); name != "" {
+			return name
+		}
+	}
+	if open := strings.Index(author, "<"); open > 0 && strings.HasSuffix(author, ">") {
+		return strings.TrimSpace(author[:open])
+	}
+	return author
+}
+
+const synthesizedGUIDPrefix = "urn:filtered-rss:"
+
+// synthesizeGUIDs gives items without a GUID a stable one derived from their
+// normalized link and title, so readers and deduplication see the same
+// identity on every run.
+func synthesizeGUIDs(items []Item) {
+	for i := range items {
+		if strings.TrimSpace(items[i].GUID.Value) != "" {
+			continue
+		}
+		hash := sha256.Sum256([]byte(normalizeURL(items[i].Link) + "\n" + strings.TrimSpace(items[i].Title)))
+		items[i].GUID = GUID{
+			Value:       fmt.Sprintf("%s%x", synthesizedGUIDPrefix, hash[:16]),
+			IsPermaLink: "false",
+		}
+	}
+}
+
+func (g GUID) synthesized() bool {
+	return strings.HasPrefix(g.Value, synthesizedGUIDPrefix)
+}
+
+// permaLink reports whether the GUID is the item's URL, which it is unless
+// isPermaLink says "false".
+func (g GUID) permaLink() bool {
+	return g.Value != "" && !strings.EqualFold(strings.TrimSpace(g.IsPermaLink), "false")
+}
+
+// cleanItems drops items that have neither a title nor a link, blanks
+// whitespace-only descriptions, removes repeated categories, and neutralizes
+// javascript:, vbscript: and data: URLs, so readers don't choke on (or get
+// attacked through) what we republish.
+func cleanItems(items []Item) []Item {
+	var cleaned []Item
+	for _, item := range items {
+		sanitizeLinks(&item)
+		if strings.TrimSpace(item.Title) == "" && strings.TrimSpace(item.Link) == "" {
+			continue
+		}
+		if strings.TrimSpace(item.Description) == "" {
+			item.Description = ""
+		}
+		seen := make(map[string]bool)
+		var categories []string
+		for _, category := range item.Categories {
+			key := strings.ToLower(strings.TrimSpace(category))
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			categories = append(categories, strings.TrimSpace(category))
+		}
+		item.Categories = categories
+		cleaned = append(cleaned, item)
+	}
+	return cleaned
 }
 
 func articleFilename(item Item) string {
-	key := item.GUID
-	if key == "" {
+	key := item.GUID.Value
+	if key == "" || item.GUID.synthesized() {
 		key = item.Link
 	}
 	hash := sha256.Sum256([]byte(key))
@@ -165,6 +910,7 @@ func articleFilename(item Item) string {
 }
 
 func saveArticlesToDir(items []Item, dir string) (int, error) {
+	items = cleanItems(items)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return 0, err
 	}
@@ -213,17 +959,31 @@ func renderItemXML(item Item) string {
 	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
 	sb.WriteString("<rss version=\"2.0\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:content=\"http://purl.org/rss/1.0/modules/content/\">\n")
 	sb.WriteString("  <channel>\n")
+	writeItemElement(&sb, item)
+	sb.WriteString("  </channel>\n")
+	sb.WriteString("</rss>\n")
+	return sb.String()
+}
+
+func writeItemElement(sb *strings.Builder, item Item) {
 	sb.WriteString("    <item>\n")
 	sb.WriteString(fmt.Sprintf("      <title>%s</title>\n", escapeXML(item.Title)))
 	sb.WriteString(fmt.Sprintf("      <link>%s</link>\n", escapeXML(item.Link)))
-	if item.GUID != "" {
-		sb.WriteString(fmt.Sprintf("      <guid>%s</guid>\n", escapeXML(item.GUID)))
+	if item.GUID.IsPermaLink != "" {
+		sb.WriteString(fmt.Sprintf("      <guid isPermaLink=\"%s\">%s</guid>\n", escapeXML(item.GUID.IsPermaLink), escapeXML(item.GUID.Value)))
+	} else if item.GUID.Value != "" {
+		sb.WriteString(fmt.Sprintf("      <guid>%s</guid>\n", escapeXML(item.GUID.Value)))
 	}
 	if item.PubDate != "" {
 		sb.WriteString(fmt.Sprintf("      <pubDate>%s</pubDate>\n", escapeXML(item.PubDate)))
 	}
-	if item.Creator != "" {
-		sb.WriteString(fmt.Sprintf("      <dc:creator>%s</dc:creator>\n", escapeXML(item.Creator)))
+	for _, creator := range item.Creators {
+		if creator != "" {
+			sb.WriteString(fmt.Sprintf("      <dc:creator>%s</dc:creator>\n", escapeXML(creator)))
+		}
+	}
+	if item.Author != "" {
+		sb.WriteString(fmt.Sprintf("      <author>%s</author>\n", escapeXML(item.Author)))
 	}
 	if item.Description != "" {
 		sb.WriteString(fmt.Sprintf("      <description>%s</description>\n", escapeXML(item.Description)))
@@ -236,28 +996,52 @@ func renderItemXML(item Item) string {
 			sb.WriteString(fmt.Sprintf("      <category>%s</category>\n", escapeXML(category)))
 		}
 	}
+	for _, extra := range item.Extra {
+		sb.WriteString(fmt.Sprintf("      %s\n", extra.render()))
+	}
 	sb.WriteString("    </item>\n")
-	sb.WriteString("  </channel>\n")
-	sb.WriteString("</rss>\n")
-	return sb.String()
 }
 
+type parsedDate struct {
+	t   time.Time
+	err error
+}
+
+// maxCachedDates bounds dateCache, which lives as long as the process, so
+// serve doesn't grow it forever. A run's feeds have far fewer dates.
+const maxCachedDates = 10000
+
+// dateCacheKey includes the default location, which naive dates are parsed
+// in.
+type dateCacheKey struct {
+	date     string
+	location *time.Location
+}
+
+// dateCache memoizes parseRSSDate, since the same dates are parsed again
+// during filtering, sorting and merging. It is emptied when full.
+var (
+	dateCacheMu sync.Mutex
+	dateCache   = make(map[dateCacheKey]parsedDate)
+)
+
 func parseRSSDate(dateStr string) (time.Time, error) {
-	formats := []string{
-		time.RFC1123Z,
-		time.RFC1123,
-		time.RFC822Z,
-		time.RFC822,
-		"2006-01-02T15:04:05Z07:00",
-		"2006-01-02",
+	key := dateCacheKey{date: dateStr, location: defaultLocation}
+	dateCacheMu.Lock()
+	cached, ok := dateCache[key]
+	dateCacheMu.Unlock()
+	if ok {
+		return cached.t, cached.err
 	}
-	dateStr = strings.TrimSpace(dateStr)
-	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t, nil
-		}
+
+	t, err := parseRSSDateUncached(dateStr)
+	dateCacheMu.Lock()
+	if len(dateCache) >= maxCachedDates {
+		dateCache = make(map[dateCacheKey]parsedDate)
 	}
-	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+	dateCache[key] = parsedDate{t: t, err: err}
+	dateCacheMu.Unlock()
+	return t, err
 }
 
 func loadAllowedAuthorsFromEnv(authorList string) map[string]bool {
@@ -272,54 +1056,154 @@ func loadAllowedAuthorsFromEnv(authorList string) map[string]bool {
 	return authors
 }
 
-func outputMarkdown(items []Item) {
+type markdownOptions struct {
+	highlight   *highlighter
+	showMatched bool
+	groupBy     string
+
+	// newestFirst orders digest periods; false for --sort date-asc.
+	newestFirst bool
+
+	// headingLevel is the Markdown heading level of the author sections
+	// (default 2); digests push it down below their period headings.
+	headingLevel int
+
+	// dateFormat, when set, adds each item's date in this Go time layout,
+	// with month and weekday names in dateLocale.
+	dateFormat string
+	dateLocale string
+
+	// relativeAge adds how long before now each item was published.
+	relativeAge bool
+	now         time.Time
+
+	// tr translates the fixed strings of the output (see --lang).
+	tr translations
+}
+
+func outputMarkdown(w io.Writer, items []Item, opts markdownOptions) {
+	items = cleanItems(items)
+	if opts.groupBy == "author" {
+		outputMarkdownByAuthor(w, items, opts)
+		return
+	}
 	for _, item := range items {
-		author := item.Creator
+		author := strings.Join(item.authors(), ", ")
 		if author == "" {
-			author = "Unknown"
+			author = opts.tr.get("Unknown")
 		}
-		fmt.Printf("- [%s](%s) - %s\n", item.Title, item.Link, author)
+		writeMarkdownItem(w, item, fmt.Sprintf(" - %s", author), opts)
 	}
 }
 
-func outputRSS(items []Item) {
-	fmt.Println(`<?xml version="1.0" encoding="UTF-8"?>`)
-	fmt.Println(`<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">`)
-	fmt.Println(`  <channel>`)
-	fmt.Println(`    <title>Filtered Technical Blog Posts</title>`)
-	fmt.Println(`    <link>https://xebia.com/blog/</link>`)
-	fmt.Println(`    <description>Filtered feed of technical blog posts</description>`)
-	fmt.Printf("    <lastBuildDate>%s</lastBuildDate>\n", time.Now().Format(time.RFC1123Z))
-
+// outputMarkdownByAuthor writes one section per author, the most prolific
+// first. Co-authored items are listed under each of their authors.
+func outputMarkdownByAuthor(w io.Writer, items []Item, opts markdownOptions) {
+	byAuthor := make(map[string][]Item)
 	for _, item := range items {
-		fmt.Println(`    <item>`)
-		fmt.Printf("      <title>%s</title>\n", escapeXML(item.Title))
-		fmt.Printf("      <link>%s</link>\n", escapeXML(item.Link))
-		if item.GUID != "" {
-			fmt.Printf("      <guid>%s</guid>\n", escapeXML(item.GUID))
+		authors := item.authors()
+		if len(authors) == 0 {
+			authors = []string{opts.tr.get("Unknown")}
 		}
-		if item.PubDate != "" {
-			fmt.Printf("      <pubDate>%s</pubDate>\n", escapeXML(item.PubDate))
+		for _, author := range authors {
+			byAuthor[author] = append(byAuthor[author], item)
 		}
-		if item.Creator != "" {
-			fmt.Printf("      <dc:creator>%s</dc:creator>\n", escapeXML(item.Creator))
-		}
-		if item.Description != "" {
-			fmt.Printf("      <description>%s</description>\n", escapeXML(item.Description))
+	}
+
+	authors := make([]string, 0, len(byAuthor))
+	for author := range byAuthor {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if len(byAuthor[authors[i]]) != len(byAuthor[authors[j]]) {
+			return len(byAuthor[authors[i]]) > len(byAuthor[authors[j]])
 		}
-		if item.Content != "" {
-			fmt.Printf("      <content:encoded><![CDATA[%s]]></content:encoded>\n", item.Content)
+		return authors[i] < authors[j]
+	})
+
+	for i, author := range authors {
+		if i > 0 {
+			fmt.Fprintln(w)
 		}
-		for _, category := range item.Categories {
-			if category != "" {
-				fmt.Printf("      <category>%s</category>\n", escapeXML(category))
+		fmt.Fprintf(w, "%s %s (%d)\n\n", markdownHeading(opts.headingLevel), author, len(byAuthor[author]))
+		for _, item := range byAuthor[author] {
+			suffix := ""
+			var coAuthors []string
+			for _, other := range item.authors() {
+				if other != author {
+					coAuthors = append(coAuthors, other)
+				}
+			}
+			if len(coAuthors) > 0 {
+				suffix = " (" + fmt.Sprintf(opts.tr.get("with %s"), strings.Join(coAuthors, ", ")) + ")"
 			}
+			writeMarkdownItem(w, item, suffix, opts)
+		}
+	}
+}
+
+func writeMarkdownItem(w io.Writer, item Item, suffix string, opts markdownOptions) {
+	if t, err := parseRSSDate(item.PubDate); err == nil {
+		var when []string
+		if opts.dateFormat != "" {
+			when = append(when, formatLocalDate(t, opts.dateFormat, opts.dateLocale))
+		}
+		if opts.relativeAge {
+			when = append(when, relativeAge(t, opts.now, opts.tr))
+		}
+		if len(when) > 0 {
+			suffix += " (" + strings.Join(when, ", ") + ")"
+		}
+	}
+	fmt.Fprintf(w, "- [%s](%s)%s\n", opts.highlight.bold(item.Title), item.Link, suffix)
+	if opts.showMatched {
+		if matched := opts.highlight.matched(item); len(matched) > 0 {
+			fmt.Fprintf(w, "  "+opts.tr.get("matched: %s")+"\n", strings.Join(matched, ", "))
 		}
-		fmt.Println(`    </item>`)
 	}
+}
 
-	fmt.Println(`  </channel>`)
-	fmt.Println(`</rss>`)
+func markdownHeading(level int) string {
+	if level < 1 {
+		level = 2
+	}
+	return strings.Repeat("#", level)
+}
+
+func outputRSS(w io.Writer, items []Item, source Channel, prov provenance) {
+	items = cleanItems(items)
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	writeProvenance(w, prov)
+	fmt.Fprintln(w, `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">`)
+	fmt.Fprintln(w, `  <channel>`)
+	fmt.Fprintln(w, `    <title>Filtered Technical Blog Posts</title>`)
+	fmt.Fprintln(w, `    <link>https://xebia.com/blog/</link>`)
+	fmt.Fprintln(w, `    <description>Filtered feed of technical blog posts</description>`)
+	if source.Language != "" {
+		fmt.Fprintf(w, "    <language>%s</language>\n", escapeXML(source.Language))
+	}
+	if source.Image != nil && source.Image.URL != "" {
+		fmt.Fprintln(w, `    <image>`)
+		fmt.Fprintf(w, "      <url>%s</url>\n", escapeXML(source.Image.URL))
+		fmt.Fprintf(w, "      <title>%s</title>\n", escapeXML(source.Image.Title))
+		fmt.Fprintf(w, "      <link>%s</link>\n", escapeXML(source.Image.Link))
+		fmt.Fprintln(w, `    </image>`)
+	}
+	built := time.Now()
+	if !prov.generated.IsZero() {
+		// The run's time, so a --replay reproduces the feed exactly.
+		built = prov.generated
+	}
+	fmt.Fprintf(w, "    <lastBuildDate>%s</lastBuildDate>\n", built.Format(time.RFC1123Z))
+
+	for _, item := range items {
+		var sb strings.Builder
+		writeItemElement(&sb, item)
+		fmt.Fprint(w, sb.String())
+	}
+
+	fmt.Fprintln(w, `  </channel>`)
+	fmt.Fprintln(w, `</rss>`)
 }
 
 func escapeXML(s string) string {
@@ -331,14 +1215,55 @@ func escapeXML(s string) string {
 	return s
 }
 
-func sortItemsByDate(items []Item) {
-	for i := 0; i < len(items)-1; i++ {
-		for j := i + 1; j < len(items); j++ {
-			date1, err1 := parseRSSDate(items[i].PubDate)
-			date2, err2 := parseRSSDate(items[j].PubDate)
-			if err1 == nil && err2 == nil && date2.After(date1) {
-				items[i], items[j] = items[j], items[i]
-			}
+var validSortOrders = map[string]bool{
+	"date-desc": true,
+	"date-asc":  true,
+	"title":     true,
+	"score":     true,
+	"source":    true,
+}
+
+// sortItems puts items in the requested output order. "source" leaves them
+// as they are: as read from the feed, or newest first after merging with
+// --merge-existing or building from --build-from, which sort by date to
+// keep the newest --max-items.
+func sortItems(items []Item, order string, undatedFirst bool, scoring *scorer) {
+	switch order {
+	case "score":
+		sortItemsByScore(items, scoring, undatedFirst)
+	case "date-desc":
+		sortItemsByDate(items, undatedFirst)
+	case "date-asc":
+		sortItemsByDate(items, !undatedFirst)
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
 		}
+	case "title":
+		sort.SliceStable(items, func(i, j int) bool {
+			title1, title2 := strings.ToLower(items[i].Title), strings.ToLower(items[j].Title)
+			if title1 != title2 {
+				return title1 < title2
+			}
+			return items[i].GUID.Value < items[j].GUID.Value
+		})
 	}
 }
+
+func sortItemsByDate(items []Item, undatedFirst bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		date1, err1 := parseRSSDate(items[i].PubDate)
+		date2, err2 := parseRSSDate(items[j].PubDate)
+		switch {
+		case err1 != nil && err2 == nil:
+			return undatedFirst
+		case err1 == nil && err2 != nil:
+			return !undatedFirst
+		case err1 == nil && err2 == nil && !date1.Equal(date2):
+			return date1.After(date2)
+		}
+		if items[i].GUID.Value != items[j].GUID.Value {
+			return items[i].GUID.Value < items[j].GUID.Value
+		}
+		return items[i].Title < items[j].Title
+	})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuthorDisplayName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"jane@example.com (Jane Doe)", "Jane Doe"},
+		{"Jane Doe <jane@example.com>", "Jane Doe"},
+		{"  Jane Doe  ", "Jane Doe"},
+		{"jane@example.com ()", "jane@example.com ()"},
+		{"<jane@example.com>", "<jane@example.com>"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := authorDisplayName(tt.in); got != tt.want {
+			t.Errorf("authorDisplayName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestItemAuthors(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want []string
+	}{
+		{"creators", Item{Creators: []string{"Alice"}, Author: "bob@example.com (Bob)"}, []string{"Alice"}},
+		{"author fallback", Item{Author: "bob@example.com (Bob)"}, []string{"Bob"}},
+		{"blank creators", Item{Creators: []string{" "}, Author: "Bob"}, []string{"Bob"}},
+		{"none", Item{}, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.item.authors(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: authors = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorsAllowed(t *testing.T) {
+	allowed := map[string]bool{"Alice": true, "Bob": true}
+	tests := []struct {
+		authors    []string
+		requireAll bool
+		want       bool
+	}{
+		{[]string{"Alice"}, false, true},
+		{[]string{"Carol", "Alice"}, false, true},
+		{[]string{"Carol"}, false, false},
+		{[]string{"Alice", "Bob"}, true, true},
+		{[]string{"Alice", "Carol"}, true, false},
+		{nil, false, false},
+		{nil, true, false},
+	}
+	for _, tt := range tests {
+		if got := authorsAllowed(tt.authors, allowed, tt.requireAll); got != tt.want {
+			t.Errorf("authorsAllowed(%q, requireAll=%v) = %v, want %v", tt.authors, tt.requireAll, got, tt.want)
+		}
+	}
+}
+
+func itemGUIDs(items []Item) []string {
+	var guids []string
+	for _, item := range items {
+		guids = append(guids, item.GUID.Value)
+	}
+	return guids
+}
+
+func TestSortItemsByDateIsDeterministic(t *testing.T) {
+	items := []Item{
+		{GUID: GUID{Value: "c"}, PubDate: "Mon, 12 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "undated"}},
+		{GUID: GUID{Value: "a"}, PubDate: "Mon, 12 Oct 2026 12:00:00 +0200"},
+		{GUID: GUID{Value: "new"}, PubDate: "Tue, 13 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "b"}, PubDate: "2026-10-12T10:00:00Z"},
+	}
+	tests := []struct {
+		undatedFirst bool
+		want         []string
+	}{
+		{false, []string{"new", "a", "b", "c", "undated"}},
+		{true, []string{"undated", "new", "a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		// Every rotation of the input sorts the same way.
+		for shift := range items {
+			rotated := append(append([]Item(nil), items[shift:]...), items[:shift]...)
+			sortItemsByDate(rotated, tt.undatedFirst)
+			if got := itemGUIDs(rotated); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("undatedFirst=%v, shift %d: got %q, want %q", tt.undatedFirst, shift, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	items := []Item{
+		{GUID: GUID{Value: "1"}, Title: "banana", PubDate: "Mon, 12 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "2"}, Title: "Apple", PubDate: "Tue, 13 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "3"}, Title: "cherry"},
+		{GUID: GUID{Value: "4"}, Title: "apple", PubDate: "Sun, 11 Oct 2026 10:00:00 +0000"},
+	}
+	tests := []struct {
+		order        string
+		undatedFirst bool
+		want         []string
+	}{
+		{"date-desc", false, []string{"2", "1", "4", "3"}},
+		{"date-desc", true, []string{"3", "2", "1", "4"}},
+		{"date-asc", false, []string{"4", "1", "2", "3"}},
+		{"date-asc", true, []string{"3", "4", "1", "2"}},
+		{"title", false, []string{"2", "4", "1", "3"}},
+		{"source", false, []string{"1", "2", "3", "4"}},
+	}
+	for _, tt := range tests {
+		sorted := append([]Item(nil), items...)
+		sortItems(sorted, tt.order, tt.undatedFirst, nil)
+		if got := itemGUIDs(sorted); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s (undatedFirst=%v): got %q, want %q", tt.order, tt.undatedFirst, got, tt.want)
+		}
+	}
+}
+
+func TestSynthesizeGUIDs(t *testing.T) {
+	items := []Item{
+		{Title: "Post", Link: "https://example.com/post/?utm_source=rss"},
+		{Title: "Post", Link: "http://EXAMPLE.com/post"},
+		{Title: "Other", Link: "https://example.com/post"},
+		{Title: "Kept", GUID: GUID{Value: "id-1"}},
+	}
+	synthesizeGUIDs(items)
+	if !items[0].GUID.synthesized() || items[0].GUID.IsPermaLink != "false" {
+		t.Errorf("GUID = %+v, want a synthesized, non-permalink GUID", items[0].GUID)
+	}
+	if items[0].GUID != items[1].GUID {
+		t.Error("the same post under equivalent links got different GUIDs")
+	}
+	if items[0].GUID == items[2].GUID {
+		t.Error("different titles under the same link got the same GUID")
+	}
+	if items[3].GUID.Value != "id-1" {
+		t.Errorf("existing GUID replaced by %q", items[3].GUID.Value)
+	}
+
+	again := []Item{{Title: "Post", Link: "https://example.com/post"}}
+	synthesizeGUIDs(again)
+	if again[0].GUID != items[0].GUID {
+		t.Error("GUIDs aren't stable across runs")
+	}
+}
+
+func TestCleanItems(t *testing.T) {
+	items := []Item{
+		{Title: "Post", Description: "  \n ", Categories: []string{"Go", " go ", "", "Data", "data"}},
+		{Title: " ", Link: ""},
+		{Link: "javascript:alert(1)"},
+		{Link: "https://example.com/untitled", Description: "Kept"},
+	}
+	cleaned := cleanItems(items)
+	if len(cleaned) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(cleaned), cleaned)
+	}
+	if cleaned[0].Description != "" {
+		t.Errorf("description = %q, want it blanked", cleaned[0].Description)
+	}
+	if want := []string{"Go", "Data"}; !reflect.DeepEqual(cleaned[0].Categories, want) {
+		t.Errorf("categories = %q, want %q", cleaned[0].Categories, want)
+	}
+	if cleaned[1].Description != "Kept" {
+		t.Errorf("description = %q, want it kept", cleaned[1].Description)
+	}
+	if items[0].Description == "" {
+		t.Error("cleanItems modified its input")
+	}
+}
+
+func TestLimitItems(t *testing.T) {
+	items := []Item{{Title: "1"}, {Title: "2"}, {Title: "3"}}
+	for _, tt := range []struct{ limit, want int }{{0, 3}, {2, 2}, {3, 3}, {5, 3}, {-1, 3}} {
+		if got := len(limitItems(items, tt.limit)); got != tt.want {
+			t.Errorf("limitItems(3 items, %d) kept %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestOutputMarkdownByAuthor(t *testing.T) {
+	items := []Item{
+		{Title: "Solo", Link: "https://example.com/1", Creators: []string{"Bob"}},
+		{Title: "Pair", Link: "https://example.com/2", Creators: []string{"Alice", "Bob"}},
+		{Title: "Anonymous", Link: "https://example.com/3"},
+	}
+	var out strings.Builder
+	outputMarkdown(&out, items, markdownOptions{groupBy: "author"})
+	want := `## Bob (2)
+
+- [Solo](https://example.com/1)
+- [Pair](https://example.com/2) (with Alice)
+
+## Alice (1)
+
+- [Pair](https://example.com/2) (with Bob)
+
+## Unknown (1)
+
+- [Anonymous](https://example.com/3)
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestCapPerAuthor(t *testing.T) {
+	items := []Item{
+		{GUID: GUID{Value: "a1"}, Creators: []string{"Alice"}},
+		{GUID: GUID{Value: "a2"}, Creators: []string{"Alice"}},
+		{GUID: GUID{Value: "ab"}, Creators: []string{"Alice", "Bob"}},
+		{GUID: GUID{Value: "b1"}, Creators: []string{"Bob"}},
+		{GUID: GUID{Value: "b2"}, Creators: []string{"Bob"}},
+		{GUID: GUID{Value: "x1"}},
+		{GUID: GUID{Value: "x2"}},
+	}
+	tests := []struct {
+		max  int
+		want []string
+	}{
+		{0, []string{"a1", "a2", "ab", "b1", "b2", "x1", "x2"}},
+		{1, []string{"a1", "b1", "x1", "x2"}},
+		{2, []string{"a1", "a2", "b1", "b2", "x1", "x2"}},
+		{3, []string{"a1", "a2", "ab", "b1", "b2", "x1", "x2"}},
+	}
+	for _, tt := range tests {
+		if got := itemGUIDs(capPerAuthor(items, tt.max)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("capPerAuthor(%d) = %q, want %q", tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that identify a campaign rather than
+// a page, so they are ignored when comparing links.
+var trackingParams = map[string]bool{
+	"fbclid": true,
+	"gclid":  true,
+	"mc_cid": true,
+	"mc_eid": true,
+	"ref":    true,
+	"_hsenc": true,
+	"_hsmi":  true,
+}
+
+// normalizeURL reduces a URL to a form where trivially different spellings
+// of the same page compare equal: scheme, case of the host, default ports,
+// trailing slashes, fragments and tracking parameters are all ignored.
+// Strings that aren't absolute URLs are returned trimmed but otherwise as-is.
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	key := "//" + host + path
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+var mergeStrategies = map[string]bool{
+	"prefer-new":       true,
+	"prefer-existing":  true,
+	"prefer-non-empty": true,
+	"longest":          true,
+}
+
+var mergeFields = []string{"title", "link", "pubDate", "authors", "description", "content", "categories", "extra"}
+
+// mergeStrategy says, per field, which copy of a duplicated item to keep.
+type mergeStrategy map[string]string
+
+// parseMergeStrategy parses "description=longest,default=prefer-new". Fields
+// not mentioned use the default, which is prefer-non-empty unless given.
+func parseMergeStrategy(spec string) (mergeStrategy, error) {
+	strategy := mergeStrategy{}
+	for _, field := range mergeFields {
+		strategy[field] = "prefer-non-empty"
+	}
+	fields := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, name, ok := strings.Cut(part, "=")
+		field, name = strings.TrimSpace(field), strings.TrimSpace(name)
+		if !ok || !mergeStrategies[name] {
+			return nil, fmt.Errorf("invalid merge strategy %q: want field=prefer-new|prefer-existing|prefer-non-empty|longest", part)
+		}
+		if _, known := strategy[field]; !known && field != "default" {
+			return nil, fmt.Errorf("unknown merge field %q (want %s or default)", field, strings.Join(mergeFields, ", "))
+		}
+		fields[field] = name
+	}
+	if name, ok := fields["default"]; ok {
+		for _, field := range mergeFields {
+			strategy[field] = name
+		}
+	}
+	for field, name := range fields {
+		if field != "default" {
+			strategy[field] = name
+		}
+	}
+	return strategy, nil
+}
+
+// pick chooses between the newer and older value of a field whose size is
+// given by size.
+func (s mergeStrategy) pick(field string, newerSize, olderSize int) (useNewer bool) {
+	switch s[field] {
+	case "prefer-existing":
+		return false
+	case "prefer-non-empty":
+		return newerSize > 0 || olderSize == 0
+	case "longest":
+		return newerSize >= olderSize
+	}
+	return true
+}
+
+// mergeItems combines two copies of the same item field by field.
+func (s mergeStrategy) mergeItems(newer, older Item) Item {
+	merged := newer
+	if !s.pick("title", len(strings.TrimSpace(newer.Title)), len(strings.TrimSpace(older.Title))) {
+		merged.Title = older.Title
+	}
+	if !s.pick("link", len(strings.TrimSpace(newer.Link)), len(strings.TrimSpace(older.Link))) {
+		merged.Link = older.Link
+	}
+	if !s.pick("pubDate", len(strings.TrimSpace(newer.PubDate)), len(strings.TrimSpace(older.PubDate))) {
+		merged.PubDate = older.PubDate
+	}
+	if !s.pick("authors", len(newer.authors()), len(older.authors())) {
+		merged.Creators, merged.Author = older.Creators, older.Author
+	}
+	if !s.pick("description", len(strings.TrimSpace(newer.Description)), len(strings.TrimSpace(older.Description))) {
+		merged.Description = older.Description
+	}
+	if !s.pick("content", len(strings.TrimSpace(newer.Content)), len(strings.TrimSpace(older.Content))) {
+		merged.Content = older.Content
+	}
+	if !s.pick("categories", len(newer.Categories), len(older.Categories)) {
+		merged.Categories = older.Categories
+	}
+	if !s.pick("extra", len(newer.Extra), len(older.Extra)) {
+		merged.Extra = older.Extra
+	}
+	return merged
+}
+
+// contentHash identifies an item by what it says rather than where it
+// lives: a SHA-256 of its title and content (its description when it has
+// none), ignoring markup, entities, case and whitespace. It is empty for
+// items without any text.
+func contentHash(item Item) string {
+	title, text := normalizedContent(item)
+	if title == "" && text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(title + "\n" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// duplicateHash is the content hash used to recognize the same post under
+// another GUID or link. It is empty for items without a body: a title alone,
+// such as "Weekly links", doesn't tell posts apart.
+func duplicateHash(item Item) string {
+	if _, text := normalizedContent(item); text == "" {
+		return ""
+	}
+	return contentHash(item)
+}
+
+func normalizedContent(item Item) (title, text string) {
+	body := item.Content
+	if strings.TrimSpace(body) == "" {
+		body = item.Description
+	}
+	normalize := func(s string) string {
+		s = html.UnescapeString(htmlTagPattern.ReplaceAllString(s, " "))
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	return normalize(item.Title), normalize(body)
+}
+
+// contentHashes maps the key of every item (see itemKey) to its content hash.
+func contentHashes(items []Item) map[string]string {
+	hashes := make(map[string]string)
+	for _, item := range items {
+		if key, hash := itemKey(item), contentHash(item); key != "" && hash != "" {
+			hashes[key] = hash
+		}
+	}
+	return hashes
+}
+
+// editedItems returns the items that previous, a map from contentHashes,
+// knows under the same key but with a different hash.
+func editedItems(items []Item, previous map[string]string) []Item {
+	var edited []Item
+	for _, item := range items {
+		old, ok := previous[itemKey(item)]
+		if hash := contentHash(item); ok && hash != "" && hash != old {
+			edited = append(edited, item)
+		}
+	}
+	return edited
+}
+
+// mergeAndDeduplicateItems combines fresh items with previously published
+// ones. Two items are the same when their GUIDs or their links match after
+// normalization, or else when their content hashes do (see duplicateHash),
+// which catches posts republished under a new GUID and link. Items with
+// different permalink GUIDs are different pages, and are never merged on
+// their content alone; synthesized and other non-permalink GUIDs don't stop
+// a match. Their fields are then combined according to strategy, with the
+// copy seen first (fresh before existing) counting as the newer one.
+func mergeAndDeduplicateItems(fresh, existing []Item, strategy mergeStrategy) []Item {
+	seenGUIDs := make(map[string]int)
+	seenLinks := make(map[string]int)
+	seenHashes := make(map[string]int)
+
+	var merged []Item
+	for _, item := range append(append([]Item(nil), fresh...), existing...) {
+		guidKey := normalizeURL(item.GUID.Value)
+		linkKey := normalizeURL(item.Link)
+		index, seen := -1, false
+		if guidKey != "" {
+			index, seen = seenGUIDs[guidKey]
+		}
+		if !seen && linkKey != "" {
+			index, seen = seenLinks[linkKey]
+		}
+		hash := duplicateHash(item)
+		if !seen && hash != "" {
+			index, seen = seenHashes[hash]
+			if seen && item.GUID.permaLink() && merged[index].GUID.permaLink() {
+				if other := normalizeURL(merged[index].GUID.Value); other != "" && other != guidKey {
+					seen = false
+				}
+			}
+		}
+		if seen {
+			merged[index] = strategy.mergeItems(merged[index], item)
+			continue
+		}
+		if guidKey != "" {
+			seenGUIDs[guidKey] = len(merged)
+		}
+		if linkKey != "" {
+			seenLinks[linkKey] = len(merged)
+		}
+		if hash != "" {
+			seenHashes[hash] = len(merged)
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
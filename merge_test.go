@@ -0,0 +1,198 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://Example.com/post/", "//example.com/post"},
+		{"http://example.com:80/post#comments", "//example.com/post"},
+		{"https://example.com/post?utm_source=rss&id=3", "//example.com/post?id=3"},
+		{"https://example.com:8443/post", "//example.com:8443/post"},
+		{"  urn:uuid:1234 ", "urn:uuid:1234"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.in); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	a := Item{Title: "Hello", Description: "<p>Some   text &amp; more</p>"}
+	b := Item{Title: "hello", Content: "some text & MORE"}
+	if contentHash(a) != contentHash(b) {
+		t.Error("contentHash differs for items that only differ in markup, entities and case")
+	}
+	if contentHash(Item{}) != "" {
+		t.Error("contentHash of an empty item is not empty")
+	}
+	if contentHash(Item{Title: "Weekly links"}) == "" {
+		t.Error("contentHash of a title-only item is empty")
+	}
+	if duplicateHash(Item{Title: "Weekly links"}) != "" {
+		t.Error("duplicateHash of a title-only item is not empty")
+	}
+	if duplicateHash(a) != contentHash(a) {
+		t.Error("duplicateHash of an item with a body differs from its contentHash")
+	}
+}
+
+func TestMergeAndDeduplicateItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		fresh     []Item
+		existing  []Item
+		wantLinks []string
+	}{
+		{
+			name:      "same GUID",
+			fresh:     []Item{{Title: "New title", Link: "https://example.com/a", GUID: GUID{Value: "id-1"}}},
+			existing:  []Item{{Title: "Old title", Link: "https://example.com/a-old", GUID: GUID{Value: "id-1"}}},
+			wantLinks: []string{"https://example.com/a"},
+		},
+		{
+			name:      "same link after normalization",
+			fresh:     []Item{{Title: "A", Link: "https://example.com/a/?utm_source=rss"}},
+			existing:  []Item{{Title: "A", Link: "http://EXAMPLE.com/a"}},
+			wantLinks: []string{"https://example.com/a/?utm_source=rss"},
+		},
+		{
+			name:      "republished under a new link",
+			fresh:     []Item{{Title: "Post", Link: "https://example.com/new", Description: "The body"}},
+			existing:  []Item{{Title: "Post", Link: "https://example.com/old", Description: "The body"}},
+			wantLinks: []string{"https://example.com/new"},
+		},
+		{
+			name:      "same title without a body",
+			fresh:     []Item{{Title: "Weekly links", Link: "https://example.com/w2"}},
+			existing:  []Item{{Title: "Weekly links", Link: "https://example.com/w1"}},
+			wantLinks: []string{"https://example.com/w2", "https://example.com/w1"},
+		},
+		{
+			name:      "republished under a new link and GUID",
+			fresh:     []Item{{Title: "Post", Link: "https://example.com/2", GUID: GUID{Value: "https://example.com/?p=2", IsPermaLink: "false"}, Description: "The body"}},
+			existing:  []Item{{Title: "Post", Link: "https://example.com/1", GUID: GUID{Value: "https://example.com/?p=1", IsPermaLink: "false"}, Description: "The body"}},
+			wantLinks: []string{"https://example.com/2"},
+		},
+		{
+			name:      "republished without GUIDs",
+			fresh:     []Item{{Title: "Post", Link: "https://example.com/2", Description: "The body"}},
+			existing:  []Item{{Title: "Post", Link: "https://example.com/1", Description: "The body"}},
+			wantLinks: []string{"https://example.com/2"},
+		},
+		{
+			name:      "same content, different permalink GUIDs",
+			fresh:     []Item{{Title: "Post", Link: "https://example.com/2", GUID: GUID{Value: "https://example.com/2"}, Description: "The body"}},
+			existing:  []Item{{Title: "Post", Link: "https://example.com/1", GUID: GUID{Value: "https://example.com/1", IsPermaLink: "true"}, Description: "The body"}},
+			wantLinks: []string{"https://example.com/2", "https://example.com/1"},
+		},
+		{
+			name:      "same content, one without a GUID",
+			fresh:     []Item{{Title: "Post", Link: "https://example.com/2", Description: "The body"}},
+			existing:  []Item{{Title: "Post", Link: "https://example.com/1", GUID: GUID{Value: "id-1"}, Description: "The body"}},
+			wantLinks: []string{"https://example.com/2"},
+		},
+	}
+	strategy, err := parseMergeStrategy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GUIDs are synthesized before every merge.
+			fresh := append([]Item(nil), tt.fresh...)
+			existing := append([]Item(nil), tt.existing...)
+			synthesizeGUIDs(fresh)
+			synthesizeGUIDs(existing)
+			merged := mergeAndDeduplicateItems(fresh, existing, strategy)
+			var links []string
+			for _, item := range merged {
+				links = append(links, item.Link)
+			}
+			if len(links) != len(tt.wantLinks) {
+				t.Fatalf("got links %q, want %q", links, tt.wantLinks)
+			}
+			for i := range links {
+				if links[i] != tt.wantLinks[i] {
+					t.Fatalf("got links %q, want %q", links, tt.wantLinks)
+				}
+			}
+		})
+	}
+}
+
+func TestEditedItems(t *testing.T) {
+	items := []Item{
+		{Title: "Edited", Link: "https://example.com/a", Description: "new text"},
+		{Title: "Same", Link: "https://example.com/b", Description: "text"},
+		{Title: "Unknown", Link: "https://example.com/c", Description: "text"},
+	}
+	previous := contentHashes([]Item{
+		{Title: "Edited", Link: "https://example.com/a", Description: "old text"},
+		{Title: "Same", Link: "https://example.com/b", Description: "text"},
+	})
+	edited := editedItems(items, previous)
+	if len(edited) != 1 || edited[0].Title != "Edited" {
+		t.Errorf("editedItems = %v, want only the edited item", edited)
+	}
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	tests := []struct {
+		spec string
+		want map[string]string
+	}{
+		{"", map[string]string{"title": "prefer-non-empty", "description": "prefer-non-empty"}},
+		{"default=prefer-new", map[string]string{"title": "prefer-new", "extra": "prefer-new"}},
+		{"description=longest, default=prefer-existing", map[string]string{"description": "longest", "title": "prefer-existing"}},
+		{"title = prefer-new,", map[string]string{"title": "prefer-new", "link": "prefer-non-empty"}},
+	}
+	for _, tt := range tests {
+		strategy, err := parseMergeStrategy(tt.spec)
+		if err != nil {
+			t.Errorf("parseMergeStrategy(%q): %v", tt.spec, err)
+			continue
+		}
+		for field, want := range tt.want {
+			if strategy[field] != want {
+				t.Errorf("parseMergeStrategy(%q)[%s] = %q, want %q", tt.spec, field, strategy[field], want)
+			}
+		}
+	}
+
+	for _, spec := range []string{"title", "title=newest", "summary=longest"} {
+		if _, err := parseMergeStrategy(spec); err == nil {
+			t.Errorf("parseMergeStrategy(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestMergeItems(t *testing.T) {
+	newer := Item{Title: "New title", Description: "Short", Creators: []string{"Jane Doe"}}
+	older := Item{Title: "Old title", Description: "A longer description", Content: "<p>Body</p>", Categories: []string{"k8s"}}
+	tests := []struct {
+		spec string
+		want Item
+	}{
+		{"", Item{Title: "New title", Description: "Short", Content: "<p>Body</p>", Creators: []string{"Jane Doe"}, Categories: []string{"k8s"}}},
+		{"default=prefer-new", newer},
+		{"default=prefer-existing", older},
+		{"default=longest", Item{Title: "New title", Description: "A longer description", Content: "<p>Body</p>", Creators: []string{"Jane Doe"}, Categories: []string{"k8s"}}},
+		{"title=prefer-existing", Item{Title: "Old title", Description: "Short", Content: "<p>Body</p>", Creators: []string{"Jane Doe"}, Categories: []string{"k8s"}}},
+	}
+	for _, tt := range tests {
+		strategy, err := parseMergeStrategy(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strategy.mergeItems(newer, older); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mergeItems with %q = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
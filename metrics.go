@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runMetrics describes one run for monitoring. It is written to
+// --metrics-file as JSON, or in the Prometheus text format when the file
+// name ends in ".prom", for node_exporter's textfile collector.
+type runMetrics struct {
+	Feed          string         `json:"feed"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Success       bool           `json:"success"`
+	FetchDuration float64        `json:"fetch_duration_seconds"`
+	HTTPStatus    int            `json:"http_status,omitempty"`
+	ItemsIn       int            `json:"items_in"`
+	ItemsOut      int            `json:"items_out"`
+	RuleHits      map[string]int `json:"rule_hits"`
+}
+
+func writeMetrics(path string, m runMetrics) error {
+	var data []byte
+	if strings.HasSuffix(path, ".prom") {
+		data = []byte(m.prometheus())
+	} else {
+		var err error
+		data, err = json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
+
+func (m runMetrics) prometheus() string {
+	var sb strings.Builder
+	feed := fmt.Sprintf(`feed="%s"`, escapePrometheusLabel(m.Feed))
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s gauge\n%s{%s} %s\n", name, help, name, name, feed, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+
+	success := 0.0
+	if m.Success {
+		success = 1
+	}
+	gauge("filtered_rss_last_run_success", "Whether the last run succeeded.", success)
+	gauge("filtered_rss_last_run_timestamp_seconds", "Unix time the last run started.", float64(m.Timestamp.Unix()))
+	gauge("filtered_rss_fetch_duration_seconds", "Time spent fetching and decoding the feed.", m.FetchDuration)
+	gauge("filtered_rss_http_status", "HTTP status of the feed request, 0 if none was received.", float64(m.HTTPStatus))
+	gauge("filtered_rss_items_in", "Items read from the feed.", float64(m.ItemsIn))
+	gauge("filtered_rss_items_out", "Items emitted after filtering and limits.", float64(m.ItemsOut))
+
+	rules := make([]string, 0, len(m.RuleHits))
+	for rule := range m.RuleHits {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	sb.WriteString("# HELP filtered_rss_items_dropped Items dropped, by the rule that dropped them.\n")
+	sb.WriteString("# TYPE filtered_rss_items_dropped gauge\n")
+	for _, rule := range rules {
+		fmt.Fprintf(&sb, "filtered_rss_items_dropped{%s,rule=\"%s\"} %d\n", feed, escapePrometheusLabel(rule), m.RuleHits[rule])
+	}
+	return sb.String()
+}
+
+func escapePrometheusLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	m := runMetrics{
+		Feed:      `https://example.com/feed?a="b"`,
+		Timestamp: time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC),
+		Success:   true,
+		ItemsIn:   10,
+		ItemsOut:  4,
+		RuleHits:  map[string]int{"since": 5, "authors": 1},
+	}
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "metrics.json")
+	if err := writeMetrics(jsonPath, m); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded runMetrics
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Success || decoded.ItemsOut != 4 || decoded.RuleHits["since"] != 5 {
+		t.Errorf("decoded metrics = %+v", decoded)
+	}
+
+	promPath := filepath.Join(dir, "metrics.prom")
+	if err := writeMetrics(promPath, m); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(promPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`filtered_rss_last_run_success{feed="https://example.com/feed?a=\"b\""} 1`,
+		`filtered_rss_last_run_timestamp_seconds{feed="https://example.com/feed?a=\"b\""} 1792130400`,
+		`filtered_rss_items_dropped{feed="https://example.com/feed?a=\"b\"",rule="authors"} 1`,
+		`filtered_rss_items_dropped{feed="https://example.com/feed?a=\"b\"",rule="since"} 5`,
+	} {
+		if !strings.Contains(string(data), want+"\n") {
+			t.Errorf("Prometheus output lacks %q:\n%s", want, data)
+		}
+	}
+}
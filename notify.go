@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// notifiedRetention is how long an announced item that left the output is
+// remembered, so one briefly dropping out of the window isn't announced again.
+const notifiedRetention = 90 * 24 * time.Hour
+
+// notifier announces new items somewhere: a webhook, Slack or email.
+type notifier interface {
+	// name is the key of the notifier's announced items in the state file.
+	name() string
+	notify(feed string, items []Item) error
+}
+
+func newNotifiers(webhookURL string, slack bool, emailTo string) ([]notifier, error) {
+	var notifiers []notifier
+	if webhookURL != "" {
+		notifiers = append(notifiers, &webhookNotifier{url: webhookURL})
+	}
+	if slack {
+		url, err := getenvSecret("SLACK_WEBHOOK_URL")
+		if err != nil {
+			return nil, err
+		}
+		if url == "" {
+			return nil, errors.New("--notify-slack requires SLACK_WEBHOOK_URL")
+		}
+		notifiers = append(notifiers, &slackNotifier{url: url})
+	}
+	if emailTo != "" {
+		n, err := newEmailNotifier(emailTo)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// notifyNewItems sends every notifier the items it hasn't announced before,
+// according to state, and records them. A notifier's first run only records
+// the items, so enabling it doesn't announce the whole rolling window. A
+// failing notifier is warned about and retried with the same items next run.
+func notifyNewItems(notifiers []notifier, state *runState, feed string, items []Item, now time.Time) {
+	if len(notifiers) == 0 {
+		return
+	}
+	if state.Notified == nil {
+		state.Notified = make(map[string]map[string]time.Time)
+	}
+	for _, n := range notifiers {
+		announced, known := state.Notified[n.name()]
+		if !known {
+			announced = make(map[string]time.Time)
+		}
+		var fresh []Item
+		for _, item := range items {
+			keys := notificationKeys(item)
+			seen := len(keys) == 0
+			for _, key := range keys {
+				if _, ok := announced[key]; ok {
+					seen = true
+				}
+			}
+			if !seen {
+				fresh = append(fresh, item)
+			}
+		}
+
+		switch {
+		case !known:
+			infof("Recorded %d items as announced to %s; only later items will be notified", len(fresh), n.name())
+		case len(fresh) > 0:
+			if err := n.notify(feed, fresh); err != nil {
+				warnf("Notifying %s: %v", n.name(), err)
+				fresh = nil
+			} else {
+				infof("Notified %s of %d new items", n.name(), len(fresh))
+			}
+		}
+		for _, item := range fresh {
+			for _, key := range notificationKeys(item) {
+				announced[key] = now
+			}
+		}
+
+		current := make(map[string]bool)
+		for _, item := range items {
+			for _, key := range notificationKeys(item) {
+				current[key] = true
+			}
+		}
+		for key, at := range announced {
+			if !current[key] && now.Sub(at) > notifiedRetention {
+				delete(announced, key)
+			}
+		}
+		state.Notified[n.name()] = announced
+	}
+}
+
+// notificationKeys identifies an item for notifications: by its key and
+// by its content hash, so a post republished under a new link isn't
+// announced again.
+func notificationKeys(item Item) []string {
+	var keys []string
+	if key := itemKey(item); key != "" {
+		keys = append(keys, key)
+	}
+	if hash := duplicateHash(item); hash != "" {
+		keys = append(keys, "sha256:"+hash)
+	}
+	return keys
+}
+
+type notificationItem struct {
+	Title       string   `json:"title"`
+	Link        string   `json:"link"`
+	GUID        string   `json:"guid,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+	PublishedAt string   `json:"published_at,omitempty"`
+	Image       string   `json:"image,omitempty"`
+}
+
+// webhookNotifier POSTs {"feed": ..., "items": [...]} as JSON.
+type webhookNotifier struct {
+	url string
+}
+
+func (n *webhookNotifier) name() string { return "webhook" }
+
+func (n *webhookNotifier) notify(feed string, items []Item) error {
+	payload := struct {
+		Feed  string             `json:"feed"`
+		Items []notificationItem `json:"items"`
+	}{Feed: feed}
+	for _, item := range cleanItems(items) {
+		entry := notificationItem{Title: item.Title, Link: item.Link, GUID: item.GUID.Value, Authors: item.authors(), Image: item.image()}
+		if t, err := parseRSSDate(item.PubDate); err == nil {
+			entry.PublishedAt = t.UTC().Format(time.RFC3339)
+		}
+		payload.Items = append(payload.Items, entry)
+	}
+	return postJSON(n.url, payload)
+}
+
+// slackNotifier posts one message listing the items to a Slack incoming
+// webhook, with their thumbnails when they have one.
+type slackNotifier struct {
+	url string
+}
+
+func (n *slackNotifier) name() string { return "slack" }
+
+func (n *slackNotifier) notify(feed string, items []Item) error {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+	heading := fmt.Sprintf("*%d new items*", len(items))
+	if feed != "" {
+		heading += " from " + escape(feed)
+	}
+	text := heading
+	blocks := []map[string]interface{}{slackSection(heading)}
+	for _, item := range cleanItems(items) {
+		line := fmt.Sprintf("<%s|%s>", item.Link, escape(item.Title))
+		if authors := item.authors(); len(authors) > 0 {
+			line += " – " + escape(strings.Join(authors, ", "))
+		}
+		text += "\n• " + line
+		block := slackSection(line)
+		if image := item.image(); image != "" {
+			block["accessory"] = map[string]string{"type": "image", "image_url": image, "alt_text": item.Title}
+		}
+		blocks = append(blocks, block)
+	}
+	payload := map[string]interface{}{"text": text}
+	// Slack accepts at most 50 blocks; longer lists are sent as plain text.
+	if len(blocks) <= 50 {
+		payload["blocks"] = blocks
+	}
+	return postJSON(n.url, payload)
+}
+
+func slackSection(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}}
+}
+
+func postJSON(url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return serviceRequest(req, nil)
+}
+
+// emailNotifier sends a plain-text email through the SMTP server in
+// SMTP_HOST, using STARTTLS when the server offers it.
+type emailNotifier struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+func newEmailNotifier(to string) (*emailNotifier, error) {
+	settings := make(map[string]string)
+	for _, name := range []string{"SMTP_HOST", "SMTP_PORT", "SMTP_FROM", "SMTP_USERNAME", "SMTP_PASSWORD"} {
+		value, err := getenvSecret(name)
+		if err != nil {
+			return nil, err
+		}
+		settings[name] = value
+	}
+	n := &emailNotifier{from: settings["SMTP_FROM"]}
+	for _, address := range strings.Split(to, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			n.to = append(n.to, address)
+		}
+	}
+	host, port := settings["SMTP_HOST"], settings["SMTP_PORT"]
+	if host == "" || n.from == "" || len(n.to) == 0 {
+		return nil, errors.New("--notify-email requires SMTP_HOST and SMTP_FROM")
+	}
+	if port == "" {
+		port = "587"
+	}
+	n.addr = net.JoinHostPort(host, port)
+	if username := settings["SMTP_USERNAME"]; username != "" {
+		n.auth = smtp.PlainAuth("", username, settings["SMTP_PASSWORD"], host)
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) name() string { return "email" }
+
+func (n *emailNotifier) notify(feed string, items []Item) error {
+	subject := fmt.Sprintf("%d new items", len(items))
+	if feed != "" {
+		subject += " from " + feed
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", n.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	for _, item := range cleanItems(items) {
+		fmt.Fprintf(&body, "%s\r\n", item.Title)
+		if authors := item.authors(); len(authors) > 0 {
+			fmt.Fprintf(&body, "by %s\r\n", strings.Join(authors, ", "))
+		}
+		fmt.Fprintf(&body, "%s\r\n\r\n", item.Link)
+	}
+	return smtp.SendMail(n.addr, n.auth, n.from, n.to, []byte(body.String()))
+}
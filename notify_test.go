@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeNotifier records the items it's asked to announce.
+type fakeNotifier struct {
+	calls [][]string
+	err   error
+}
+
+func (n *fakeNotifier) name() string { return "fake" }
+
+func (n *fakeNotifier) notify(feed string, items []Item) error {
+	var links []string
+	for _, item := range items {
+		links = append(links, item.Link)
+	}
+	n.calls = append(n.calls, links)
+	return n.err
+}
+
+func notifyTestItem(link, description string) Item {
+	return Item{Title: "Post", Link: link, Description: description}
+}
+
+func TestNotifyNewItems(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	first := notifyTestItem("https://example.com/1", "First post")
+	second := notifyTestItem("https://example.com/2", "Second post")
+	republished := notifyTestItem("https://example.com/1-moved", "First post")
+
+	n := &fakeNotifier{}
+	var state runState
+	runs := []struct {
+		items []Item
+		err   error
+		want  []string
+	}{
+		// The first run only records what's already in the feed.
+		{[]Item{first}, nil, nil},
+		{[]Item{first, second}, nil, []string{"https://example.com/2"}},
+		{[]Item{first, second}, nil, nil},
+		// Same content under a new link.
+		{[]Item{republished, second}, nil, nil},
+	}
+	for i, run := range runs {
+		n.calls, n.err = nil, run.err
+		notifyNewItems([]notifier{n}, &state, "feed", run.items, now)
+		var got []string
+		if len(n.calls) > 0 {
+			got = n.calls[0]
+		}
+		if !reflect.DeepEqual(got, run.want) {
+			t.Errorf("run %d notified %v, want %v", i+1, got, run.want)
+		}
+	}
+}
+
+func TestNotifyNewItemsRetriesFailures(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	item := notifyTestItem("https://example.com/1", "First post")
+	state := runState{Notified: map[string]map[string]time.Time{"fake": {}}}
+
+	failing := &fakeNotifier{err: errors.New("unreachable")}
+	captureOutput(t, &os.Stderr, func() {
+		notifyNewItems([]notifier{failing}, &state, "feed", []Item{item}, now)
+	})
+	if len(state.Notified["fake"]) != 0 {
+		t.Errorf("failed notification recorded %v", state.Notified["fake"])
+	}
+
+	working := &fakeNotifier{}
+	notifyNewItems([]notifier{working}, &state, "feed", []Item{item}, now)
+	if len(working.calls) != 1 {
+		t.Errorf("item was notified %d times after a failure, want 1", len(working.calls))
+	}
+}
+
+func TestNotifyNewItemsForgetsOldItems(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	state := runState{Notified: map[string]map[string]time.Time{"fake": {
+		"//example.com/old":    now.Add(-notifiedRetention - time.Hour),
+		"//example.com/recent": now.Add(-time.Hour),
+		"//example.com/kept":   now.Add(-notifiedRetention - time.Hour),
+	}}}
+	kept := Item{Link: "https://example.com/kept"}
+	notifyNewItems([]notifier{&fakeNotifier{}}, &state, "feed", []Item{kept}, now)
+
+	for _, key := range []string{"//example.com/recent", "//example.com/kept"} {
+		if _, ok := state.Notified["fake"][key]; !ok {
+			t.Errorf("%s was forgotten", key)
+		}
+	}
+	if _, ok := state.Notified["fake"]["//example.com/old"]; ok {
+		t.Error("expired key //example.com/old was kept")
+	}
+}
+
+func TestNotificationKeys(t *testing.T) {
+	if keys := notificationKeys(Item{Link: "https://example.com/1"}); len(keys) != 1 {
+		t.Errorf("keys of an item without content = %v, want only its link", keys)
+	}
+	a := notificationKeys(notifyTestItem("https://example.com/1", "<p>Same  text</p>"))
+	b := notificationKeys(notifyTestItem("https://example.com/2", "same text"))
+	if len(a) != 2 || len(b) != 2 || a[1] != b[1] {
+		t.Errorf("keys %v and %v should share a content hash", a, b)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload struct {
+		Feed  string             `json:"feed"`
+		Items []notificationItem `json:"items"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	items := []Item{{
+		Title:   "Kubernetes & you",
+		Link:    "https://example.com/1",
+		PubDate: "Wed, 14 Oct 2026 12:00:00 +0200",
+	}}
+	n := &webhookNotifier{url: server.URL}
+	if err := n.notify("Data blog", items); err != nil {
+		t.Fatal(err)
+	}
+	want := notificationItem{Title: "Kubernetes & you", Link: "https://example.com/1", PublishedAt: "2026-10-14T10:00:00Z"}
+	if payload.Feed != "Data blog" || len(payload.Items) != 1 || !reflect.DeepEqual(payload.Items[0], want) {
+		t.Errorf("payload = %+v, want one item %+v", payload, want)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := (&webhookNotifier{url: failing.URL}).notify("", items); err == nil {
+		t.Error("webhook returning 500 didn't fail")
+	}
+}
+
+func TestNewNotifiers(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK_URL", "")
+	t.Setenv("SMTP_HOST", "")
+	if _, err := newNotifiers("", true, ""); err == nil {
+		t.Error("--notify-slack without SLACK_WEBHOOK_URL succeeded")
+	}
+	if _, err := newNotifiers("", false, "me@example.com"); err == nil {
+		t.Error("--notify-email without SMTP_HOST succeeded")
+	}
+
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_FROM", "feed@example.com")
+	notifiers, err := newNotifiers("https://example.com/hook", false, "a@example.com, b@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := notifiers[1].(*emailNotifier)
+	if email.addr != "smtp.example.com:587" || !reflect.DeepEqual(email.to, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("email notifier = %+v", email)
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// outputParquet writes items as a Parquet file with one row group. Columns
+// are PLAIN-encoded and uncompressed, which keeps the writer small and is
+// read by every Parquet implementation; the files are tiny either way.
+//
+// The schema is:
+//
+//	optional binary guid (UTF8);
+//	optional binary link (UTF8);
+//	optional binary title (UTF8);
+//	optional int64 published_at (TIMESTAMP_MICROS);
+//	required group authors (LIST) { repeated group list { required binary element (UTF8); } }
+//	required group categories (LIST) { repeated group list { required binary element (UTF8); } }
+//	optional binary description (UTF8);
+//	optional binary content (UTF8);
+func outputParquet(w io.Writer, items []Item) error {
+	items = cleanItems(items)
+	columns := []*parquetColumn{
+		newParquetStringColumn("guid"),
+		newParquetStringColumn("link"),
+		newParquetStringColumn("title"),
+		{name: "published_at", physicalType: parquetInt64, convertedType: parquetTimestampMicros, maxDef: 1},
+		newParquetListColumn("authors"),
+		newParquetListColumn("categories"),
+		newParquetStringColumn("description"),
+		newParquetStringColumn("content"),
+	}
+	for _, item := range items {
+		columns[0].addString(item.GUID.Value)
+		columns[1].addString(item.Link)
+		columns[2].addString(item.Title)
+		if t, err := parseRSSDate(item.PubDate); err == nil {
+			columns[3].addInt64(t.UnixNano() / int64(time.Microsecond))
+		} else {
+			columns[3].addNull()
+		}
+		columns[4].addList(item.authors())
+		columns[5].addList(item.Categories)
+		columns[6].addString(item.Description)
+		columns[7].addString(item.Content)
+	}
+
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+	var chunks []parquetChunk
+	var totalSize int64
+	for _, column := range columns {
+		chunk := column.writeChunk(&file)
+		totalSize += chunk.size
+		chunks = append(chunks, chunk)
+	}
+
+	footer := &thriftWriter{}
+	writeParquetFooter(footer, columns, chunks, int64(len(items)), totalSize)
+	file.Write(footer.buf.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(footer.buf.Len()))
+	file.WriteString("PAR1")
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// Parquet enum values, from parquet.thrift.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetRepeated = 2
+
+	parquetUTF8            = 0
+	parquetList            = 3
+	parquetTimestampMicros = 10
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+	parquetDataPage      = 0
+	parquetUncompressed  = 0
+)
+
+type parquetColumn struct {
+	name          string
+	physicalType  int32
+	convertedType int32
+	isList        bool
+	maxDef        int
+	maxRep        int
+
+	defLevels []int
+	repLevels []int
+	values    bytes.Buffer
+}
+
+type parquetChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+func newParquetStringColumn(name string) *parquetColumn {
+	return &parquetColumn{name: name, physicalType: parquetByteArray, convertedType: parquetUTF8, maxDef: 1}
+}
+
+func newParquetListColumn(name string) *parquetColumn {
+	return &parquetColumn{name: name, physicalType: parquetByteArray, convertedType: parquetUTF8, isList: true, maxDef: 1, maxRep: 1}
+}
+
+// addString adds an optional string; empty strings are stored as null.
+func (c *parquetColumn) addString(value string) {
+	if value == "" {
+		c.addNull()
+		return
+	}
+	c.defLevels = append(c.defLevels, 1)
+	c.writeByteArray(value)
+}
+
+func (c *parquetColumn) addInt64(value int64) {
+	c.defLevels = append(c.defLevels, 1)
+	binary.Write(&c.values, binary.LittleEndian, value)
+}
+
+func (c *parquetColumn) addNull() {
+	c.defLevels = append(c.defLevels, 0)
+}
+
+// addList adds one row of a list column. An empty list is a single entry
+// at definition level 0; every element after the first repeats at level 1.
+func (c *parquetColumn) addList(values []string) {
+	if len(values) == 0 {
+		c.defLevels = append(c.defLevels, 0)
+		c.repLevels = append(c.repLevels, 0)
+		return
+	}
+	for i, value := range values {
+		c.defLevels = append(c.defLevels, 1)
+		c.repLevels = append(c.repLevels, min(i, 1))
+		c.writeByteArray(value)
+	}
+}
+
+func (c *parquetColumn) writeByteArray(value string) {
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(value)))
+	c.values.WriteString(value)
+}
+
+// writeChunk writes the column as a single data page.
+func (c *parquetColumn) writeChunk(file *bytes.Buffer) parquetChunk {
+	var page bytes.Buffer
+	if c.maxRep > 0 {
+		writeParquetLevels(&page, c.repLevels)
+	}
+	if c.maxDef > 0 {
+		writeParquetLevels(&page, c.defLevels)
+	}
+	page.Write(c.values.Bytes())
+
+	numValues := len(c.defLevels)
+	header := &thriftWriter{}
+	header.i32Field(1, parquetDataPage)
+	header.i32Field(2, int32(page.Len()))
+	header.i32Field(3, int32(page.Len()))
+	header.structField(5)
+	header.i32Field(1, int32(numValues))
+	header.i32Field(2, parquetEncodingPlain)
+	header.i32Field(3, parquetEncodingRLE)
+	header.i32Field(4, parquetEncodingRLE)
+	header.endStruct()
+	header.endStruct()
+
+	offset := int64(file.Len())
+	file.Write(header.buf.Bytes())
+	file.Write(page.Bytes())
+	return parquetChunk{offset: offset, size: int64(file.Len()) - offset, numValues: int64(numValues)}
+}
+
+// writeParquetLevels writes levels (all 0 or 1, so one bit wide) in the
+// RLE/bit-packing hybrid encoding, using RLE runs only, prefixed by their
+// length in bytes.
+func writeParquetLevels(page *bytes.Buffer, levels []int) {
+	var runs bytes.Buffer
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		writeUvarint(&runs, uint64(j-i)<<1)
+		runs.WriteByte(byte(levels[i]))
+		i = j
+	}
+	binary.Write(page, binary.LittleEndian, uint32(runs.Len()))
+	page.Write(runs.Bytes())
+}
+
+func writeParquetFooter(t *thriftWriter, columns []*parquetColumn, chunks []parquetChunk, numRows, totalSize int64) {
+	t.i32Field(1, 1) // version
+
+	// The schema is flattened depth-first, starting with the root.
+	elements := 1
+	for _, column := range columns {
+		if column.isList {
+			elements += 3
+		} else {
+			elements++
+		}
+	}
+	t.listField(2, thriftStruct, elements)
+	t.beginStruct()
+	t.stringField(4, "schema")
+	t.i32Field(5, int32(len(columns)))
+	t.endStruct()
+	for _, column := range columns {
+		if column.isList {
+			t.beginStruct()
+			t.i32Field(3, parquetRequired)
+			t.stringField(4, column.name)
+			t.i32Field(5, 1)
+			t.i32Field(6, parquetList)
+			t.endStruct()
+			t.beginStruct()
+			t.i32Field(3, parquetRepeated)
+			t.stringField(4, "list")
+			t.i32Field(5, 1)
+			t.endStruct()
+			t.beginStruct()
+			t.i32Field(1, column.physicalType)
+			t.i32Field(3, parquetRequired)
+			t.stringField(4, "element")
+			t.i32Field(6, column.convertedType)
+			t.endStruct()
+			continue
+		}
+		t.beginStruct()
+		t.i32Field(1, column.physicalType)
+		t.i32Field(3, parquetOptional)
+		t.stringField(4, column.name)
+		t.i32Field(6, column.convertedType)
+		t.endStruct()
+	}
+
+	t.i64Field(3, numRows)
+
+	t.listField(4, thriftStruct, 1)
+	t.beginStruct()
+	t.listField(1, thriftStruct, len(columns))
+	for i, column := range columns {
+		chunk := chunks[i]
+		t.beginStruct()
+		t.i64Field(2, chunk.offset)
+		t.structField(3)
+		t.i32Field(1, column.physicalType)
+		t.listField(2, thriftI32, 2)
+		t.writeZigzag(parquetEncodingPlain)
+		t.writeZigzag(parquetEncodingRLE)
+		path := []string{column.name}
+		if column.isList {
+			path = append(path, "list", "element")
+		}
+		t.listField(3, thriftBinary, len(path))
+		for _, part := range path {
+			t.writeBinary(part)
+		}
+		t.i32Field(4, parquetUncompressed)
+		t.i64Field(5, chunk.numValues)
+		t.i64Field(6, chunk.size)
+		t.i64Field(7, chunk.size)
+		t.i64Field(9, chunk.offset)
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64Field(2, totalSize)
+	t.i64Field(3, numRows)
+	t.endStruct()
+
+	t.stringField(6, "filtered-data-rss")
+	t.endStruct()
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes the subset of the Thrift compact protocol Parquet
+// metadata needs. The top-level struct is implicit: fields can be written
+// right away, and the final endStruct closes it.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	lastIDs []int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.writeZigzag(int64(id))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) writeZigzag(n int64) {
+	writeUvarint(&t.buf, uint64(n<<1^n>>63))
+}
+
+func (t *thriftWriter) writeBinary(s string) {
+	writeUvarint(&t.buf, uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) i32Field(id int16, n int32) {
+	t.fieldHeader(id, thriftI32)
+	t.writeZigzag(int64(n))
+}
+
+func (t *thriftWriter) i64Field(id int16, n int64) {
+	t.fieldHeader(id, thriftI64)
+	t.writeZigzag(n)
+}
+
+func (t *thriftWriter) stringField(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.writeBinary(s)
+}
+
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	t.buf.WriteByte(0xF0 | elemType)
+	writeUvarint(&t.buf, uint64(size))
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginStruct()
+}
+
+// beginStruct starts a struct that is a list element or field value.
+func (t *thriftWriter) beginStruct() {
+	t.lastIDs = append(t.lastIDs, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	if n := len(t.lastIDs); n > 0 {
+		t.lastID = t.lastIDs[n-1]
+		t.lastIDs = t.lastIDs[:n-1]
+	}
+}
+
+func writeUvarint(buf *bytes.Buffer, n uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], n)])
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOutputParquetRoundTrip reads the file back with a minimal reader,
+// independent of the writer: Thrift metadata is decoded generically and each
+// column's page is decoded into rows.
+func TestOutputParquetRoundTrip(t *testing.T) {
+	items := []Item{
+		{
+			Title:       "First",
+			Link:        "https://example.com/1",
+			GUID:        GUID{Value: "id-1"},
+			PubDate:     "Mon, 12 Oct 2026 10:00:00 +0000",
+			Creators:    []string{"Alice", "Bob"},
+			Categories:  []string{"go", "data", "parquet"},
+			Description: "Summary",
+			Content:     "<p>Body</p>",
+		},
+		{Title: "Second", PubDate: "not a date"},
+		{Link: "https://example.com/3", Author: "carol@example.com (Carol)", Categories: []string{"solo"}},
+	}
+	var buf bytes.Buffer
+	if err := outputParquet(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatal("missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := &thriftReader{data: file[len(file)-8-footerLen : len(file)-8]}
+	meta := footer.readStruct()
+	if footer.err != nil {
+		t.Fatalf("reading footer: %v", footer.err)
+	}
+	if footer.pos != len(footer.data) {
+		t.Fatalf("footer has %d trailing bytes", len(footer.data)-footer.pos)
+	}
+	if meta[3] != int64(len(items)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(items))
+	}
+
+	var schema []string
+	for _, element := range meta[2].([]any) {
+		schema = append(schema, element.(map[int16]any)[4].(string))
+	}
+	wantSchema := "schema guid link title published_at authors list element categories list element description content"
+	if got := strings.Join(schema, " "); got != wantSchema {
+		t.Errorf("schema = %q, want %q", got, wantSchema)
+	}
+
+	rowGroups := meta[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatalf("got %d row groups, want 1", len(rowGroups))
+	}
+	columns := make(map[string][]any)
+	for _, chunk := range rowGroups[0].(map[int16]any)[1].([]any) {
+		md := chunk.(map[int16]any)[3].(map[int16]any)
+		var path []string
+		for _, part := range md[3].([]any) {
+			path = append(path, part.(string))
+		}
+		rows, err := readParquetColumn(file, md)
+		if err != nil {
+			t.Fatalf("column %s: %v", strings.Join(path, "."), err)
+		}
+		columns[path[0]] = rows
+	}
+
+	published := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC).UnixMicro()
+	want := map[string][]any{
+		"guid":         {"id-1", nil, nil},
+		"link":         {"https://example.com/1", nil, "https://example.com/3"},
+		"title":        {"First", "Second", nil},
+		"published_at": {published, nil, nil},
+		"authors":      {[]string{"Alice", "Bob"}, []string(nil), []string{"Carol"}},
+		"categories":   {[]string{"go", "data", "parquet"}, []string(nil), []string{"solo"}},
+		"description":  {"Summary", nil, nil},
+		"content":      {"<p>Body</p>", nil, nil},
+	}
+	for name, wantRows := range want {
+		if got := columns[name]; !reflect.DeepEqual(got, wantRows) {
+			t.Errorf("column %s = %#v, want %#v", name, got, wantRows)
+		}
+	}
+}
+
+// readParquetColumn decodes the single PLAIN, uncompressed data page of a
+// column chunk into one value per row: a string, an int64 or nil for flat
+// columns, and a []string for lists.
+func readParquetColumn(file []byte, md map[int16]any) ([]any, error) {
+	offset := int(md[9].(int64))
+	r := &thriftReader{data: file, pos: offset}
+	header := r.readStruct()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if header[1] != int32(parquetDataPage) || md[4] != int32(parquetUncompressed) {
+		return nil, fmt.Errorf("unexpected page type %v or codec %v", header[1], md[4])
+	}
+	size := int(header[3].(int32))
+	if md[6] != int64(r.pos-offset+size) {
+		return nil, fmt.Errorf("chunk size %v, but header and page take %d bytes", md[6], r.pos-offset+size)
+	}
+	page := file[r.pos : r.pos+size]
+	numValues := int(header[5].(map[int16]any)[1].(int32))
+
+	isList := len(md[3].([]any)) > 1
+	var repLevels []int
+	var err error
+	if isList {
+		if repLevels, page, err = readParquetLevels(page, numValues); err != nil {
+			return nil, err
+		}
+	}
+	defLevels, page, err := readParquetLevels(page, numValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []any
+	for i, def := range defLevels {
+		var value any
+		if def == 1 {
+			switch md[1] {
+			case int32(parquetByteArray):
+				if len(page) < 4 {
+					return nil, fmt.Errorf("value %d truncated", i)
+				}
+				n := int(binary.LittleEndian.Uint32(page))
+				if len(page) < 4+n {
+					return nil, fmt.Errorf("value %d truncated", i)
+				}
+				value, page = string(page[4:4+n]), page[4+n:]
+			case int32(parquetInt64):
+				if len(page) < 8 {
+					return nil, fmt.Errorf("value %d truncated", i)
+				}
+				value, page = int64(binary.LittleEndian.Uint64(page)), page[8:]
+			default:
+				return nil, fmt.Errorf("unexpected physical type %v", md[1])
+			}
+		}
+		switch {
+		case !isList:
+			rows = append(rows, value)
+		case repLevels[i] == 0 && value == nil:
+			rows = append(rows, []string(nil))
+		case repLevels[i] == 0:
+			rows = append(rows, []string{value.(string)})
+		default:
+			rows[len(rows)-1] = append(rows[len(rows)-1].([]string), value.(string))
+		}
+	}
+	if len(page) > 0 {
+		return nil, fmt.Errorf("%d bytes left after the values", len(page))
+	}
+	return rows, nil
+}
+
+// readParquetLevels decodes count one-bit levels in the length-prefixed
+// RLE/bit-packing hybrid encoding and returns the rest of the page.
+func readParquetLevels(page []byte, count int) ([]int, []byte, error) {
+	if len(page) < 4 {
+		return nil, nil, fmt.Errorf("levels truncated")
+	}
+	n := int(binary.LittleEndian.Uint32(page))
+	data, rest := page[4:4+n], page[4+n:]
+	var levels []int
+	for len(data) > 0 {
+		header, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, nil, fmt.Errorf("bad run header")
+		}
+		data = data[size:]
+		if header&1 == 1 {
+			// A bit-packed run of header>>1 groups of eight values.
+			groups := int(header >> 1)
+			if len(data) < groups {
+				return nil, nil, fmt.Errorf("bit-packed run truncated")
+			}
+			for _, b := range data[:groups] {
+				for bit := 0; bit < 8; bit++ {
+					levels = append(levels, int(b>>bit&1))
+				}
+			}
+			data = data[groups:]
+			continue
+		}
+		if len(data) < 1 {
+			return nil, nil, fmt.Errorf("RLE run truncated")
+		}
+		for i := uint64(0); i < header>>1; i++ {
+			levels = append(levels, int(data[0]))
+		}
+		data = data[1:]
+	}
+	if len(levels) < count {
+		return nil, nil, fmt.Errorf("got %d levels, want %d", len(levels), count)
+	}
+	return levels[:count], rest, nil
+}
+
+// thriftReader decodes the Thrift compact protocol without a schema: structs
+// become maps from field id to value.
+type thriftReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *thriftReader) byte() byte {
+	if r.pos >= len(r.data) {
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected end of data at %d", r.pos)
+		}
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	n, size := binary.Uvarint(r.data[min(r.pos, len(r.data)):])
+	if size <= 0 {
+		if r.err == nil {
+			r.err = fmt.Errorf("bad varint at %d", r.pos)
+		}
+		return 0
+	}
+	r.pos += size
+	return n
+}
+
+func (r *thriftReader) zigzag() int64 {
+	n := r.uvarint()
+	return int64(n>>1) ^ -int64(n&1)
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var id int16
+	for r.err == nil {
+		header := r.byte()
+		if header == 0 {
+			break
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		switch fieldType := header & 0x0F; fieldType {
+		case 1, 2:
+			fields[id] = fieldType == 1
+		default:
+			fields[id] = r.readValue(fieldType)
+		}
+	}
+	return fields
+}
+
+func (r *thriftReader) readValue(valueType byte) any {
+	switch valueType {
+	case 1, 2, 3:
+		return r.byte()
+	case 4:
+		return int16(r.zigzag())
+	case thriftI32:
+		return int32(r.zigzag())
+	case thriftI64:
+		return r.zigzag()
+	case 7:
+		r.pos += 8
+		return nil
+	case thriftBinary:
+		n := int(r.uvarint())
+		if r.pos+n > len(r.data) {
+			r.err = fmt.Errorf("binary of %d bytes runs past the end", n)
+			return ""
+		}
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList, 10:
+		header := r.byte()
+		size, elemType := int(header>>4), header&0x0F
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		var list []any
+		for i := 0; i < size && r.err == nil; i++ {
+			list = append(list, r.readValue(elemType))
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	if r.err == nil {
+		r.err = fmt.Errorf("unsupported Thrift type %d", valueType)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+// Without it, the module version or VCS revision from the build info is used.
+var version = ""
+
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return "devel-" + revision
+}
+
+// provenance records how a feed was produced. outputRSS writes it as a
+// comment at the top of the feed, with sources reduced by publicSource;
+// the zero value writes nothing.
+type provenance struct {
+	generated time.Time
+	sources   []string
+	filters   []itemFilter
+}
+
+// rulesHash identifies the filter rules of a run: the filters and their
+// settings, such as the allowed authors and score weights. Runs with the same
+// hash filter the same way.
+func rulesHash(filters []itemFilter) string {
+	if len(filters) == 0 {
+		return "none"
+	}
+	h := sha256.New()
+	for _, filter := range filters {
+		fmt.Fprintf(h, "%s\t%s\n", filter.name, filter.rule)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// publicSource returns what of a source can be published: credentials, the
+// query string, which often carries API keys, and the fragment are dropped
+// from URLs, and local paths are reduced to their last element.
+func publicSource(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return filepath.Base(source)
+	}
+	u.User = nil
+	u.RawQuery, u.ForceQuery = "", false
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+func writeProvenance(w io.Writer, p provenance) {
+	if p.generated.IsZero() {
+		return
+	}
+	// "--" may not appear inside an XML comment.
+	escape := func(s string) string { return strings.ReplaceAll(s, "--", "-%2D") }
+
+	fmt.Fprintln(w, "<!--")
+	fmt.Fprintf(w, "  Generated by filtered-data-rss %s\n", escape(toolVersion()))
+	fmt.Fprintf(w, "  Run: %s\n", p.generated.UTC().Format(time.RFC3339))
+	for _, source := range p.sources {
+		if source != "" {
+			fmt.Fprintf(w, "  Source: %s\n", escape(publicSource(source)))
+		}
+	}
+	names := make([]string, 0, len(p.filters))
+	for _, filter := range p.filters {
+		names = append(names, filter.name)
+	}
+	rules := rulesHash(p.filters)
+	if len(names) > 0 {
+		rules += " (" + strings.Join(names, ", ") + ")"
+	}
+	fmt.Fprintf(w, "  Filter rules: %s\n", rules)
+	fmt.Fprintln(w, "-->")
+}
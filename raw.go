@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// RawElement is an item child element the tool doesn't model. It keeps the
+// element's name, attributes and re-serialized children so it can be written
+// back out unchanged in meaning.
+type RawElement struct {
+	Name  xml.Name
+	Attrs []xml.Attr
+	Inner string
+}
+
+func (e *RawElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	e.Name = start.Name
+	e.Attrs = withoutNamespaceDecls(start.Attr)
+
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			t.Attr = withoutNamespaceDecls(t.Attr)
+			token = t
+		case xml.EndElement:
+			if depth == 0 {
+				if err := encoder.Flush(); err != nil {
+					return err
+				}
+				e.Inner = buf.String()
+				return nil
+			}
+			depth--
+		case xml.ProcInst, xml.Directive:
+			continue
+		}
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			return err
+		}
+	}
+}
+
+// withoutNamespaceDecls drops xmlns attributes. Names are already resolved to
+// namespace URLs by the decoder, and render declares what it needs.
+func withoutNamespaceDecls(attrs []xml.Attr) []xml.Attr {
+	var kept []xml.Attr
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			continue
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+func (e RawElement) render() string {
+	var sb strings.Builder
+	sb.WriteString("<" + e.Name.Local)
+	if e.Name.Space != "" {
+		sb.WriteString(fmt.Sprintf(` xmlns="%s"`, escapeXML(e.Name.Space)))
+	}
+	prefixes := make(map[string]string)
+	for _, attr := range e.Attrs {
+		name := attr.Name.Local
+		switch attr.Name.Space {
+		case "":
+		case "xml", "http://www.w3.org/XML/1998/namespace":
+			name = "xml:" + name
+		default:
+			prefix, ok := prefixes[attr.Name.Space]
+			if !ok {
+				prefix = fmt.Sprintf("ns%d", len(prefixes)+1)
+				prefixes[attr.Name.Space] = prefix
+				sb.WriteString(fmt.Sprintf(` xmlns:%s="%s"`, prefix, escapeXML(attr.Name.Space)))
+			}
+			name = prefix + ":" + name
+		}
+		sb.WriteString(fmt.Sprintf(` %s="%s"`, name, escapeXML(attr.Value)))
+	}
+	if e.Inner == "" {
+		sb.WriteString("/>")
+		return sb.String()
+	}
+	sb.WriteString(">" + e.Inner + "</" + e.Name.Local + ">")
+	return sb.String()
+}
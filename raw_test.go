@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRawElementRoundTrip(t *testing.T) {
+	doc := `<rss xmlns:media="http://search.yahoo.com/mrss/" xmlns:geo="http://www.w3.org/2003/01/geo/wgs84_pos#"><channel><item>
+<title>Photo</title>
+<enclosure url="https://example.com/a.jpg" type="image/jpeg" length="10"/>
+<media:content url="https://example.com/b.jpg" medium="image" xml:lang="nl">
+  <media:title type="plain">A &amp; B</media:title>
+  <media:credit role="author" geo:lat="52.1">Jane</media:credit>
+</media:content>
+<source url="https://example.com/feed">Example</source>
+</item></channel></rss>`
+	items := decodeTestFeed(t, doc, decodeOptions{}).Items
+	if len(items) != 1 || len(items[0].Extra) != 3 {
+		t.Fatalf("items = %+v, want one item with three extra elements", items)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<rss><channel>\n")
+	writeItemElement(&sb, items[0])
+	sb.WriteString("</channel></rss>")
+	again := decodeTestFeed(t, sb.String(), decodeOptions{}).Items
+	if len(again) != 1 {
+		t.Fatalf("re-decoded %d items from\n%s", len(again), sb.String())
+	}
+	for i, want := range items[0].Extra {
+		got := again[0].Extra[i]
+		if got.Name != want.Name || !reflect.DeepEqual(got.Attrs, want.Attrs) {
+			t.Errorf("extra %d = %v %v, want %v %v", i, got.Name, got.Attrs, want.Name, want.Attrs)
+		}
+		if normalizeSpace(got.Inner) != normalizeSpace(want.Inner) {
+			t.Errorf("extra %d inner = %q, want %q", i, got.Inner, want.Inner)
+		}
+	}
+	if !strings.Contains(items[0].Extra[1].Inner, "A &amp; B") {
+		t.Errorf("media:content inner = %q, want the title escaped", items[0].Extra[1].Inner)
+	}
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// recordedRequestHeader is added to every recorded response, naming the
+// request it answered, so recordings can be told apart when debugging.
+const recordedRequestHeader = "X-Recorded-Request"
+
+// recordingRun is the run.json of a recording directory. Replays run at the
+// recorded time, so --since and relative dates select the same items.
+type recordingRun struct {
+	Started time.Time `json:"started"`
+	Args    []string  `json:"args"`
+}
+
+// recordingFile names the file holding the n-th response to method and
+// rawURL. Counting requests keeps repeated ones, such as polling a BigQuery
+// job, apart.
+func recordingFile(dir, method, rawURL string, n int) string {
+	sum := sha256.Sum256([]byte(method + " " + rawURL))
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.http", hex.EncodeToString(sum[:8]), n))
+}
+
+// requestCounter numbers the requests to each method and URL.
+type requestCounter struct {
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+func (c *requestCounter) next(method, rawURL string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen == nil {
+		c.seen = make(map[string]int)
+	}
+	c.seen[method+" "+rawURL]++
+	return c.seen[method+" "+rawURL]
+}
+
+// startRecording prepares dir for --record: it is created if needed, and
+// the run's time and arguments are written to run.json.
+func startRecording(dir string, started time.Time, args []string) error {
+	data, err := json.MarshalIndent(recordingRun{Started: started, Args: args}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "run.json"), append(data, '\n'))
+}
+
+// recordedRunTime returns when the run recorded in dir started.
+func recordedRunTime(dir string) (time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "run.json"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var run recordingRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return time.Time{}, fmt.Errorf("parsing run.json: %w", err)
+	}
+	return run.Started, nil
+}
+
+// recordingTransport saves every response it receives to dir, in the HTTP
+// wire format, with the body as received after decompression.
+type recordingTransport struct {
+	base    http.RoundTripper
+	dir     string
+	counter requestCounter
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	saved := *resp
+	saved.Header = resp.Header.Clone()
+	saved.Header.Set(recordedRequestHeader, req.Method+" "+req.URL.String())
+	saved.Header.Del("Content-Length")
+	saved.Proto, saved.ProtoMajor, saved.ProtoMinor = "HTTP/1.1", 1, 1
+	saved.ContentLength = int64(len(body))
+	saved.TransferEncoding = nil
+	saved.Body = io.NopCloser(bytes.NewReader(body))
+	dump, err := httputil.DumpResponse(&saved, true)
+	if err != nil {
+		return nil, fmt.Errorf("recording %s: %w", req.URL.Redacted(), err)
+	}
+	path := recordingFile(t.dir, req.Method, req.URL.String(), t.counter.next(req.Method, req.URL.String()))
+	if err := writeFileAtomic(path, dump); err != nil {
+		return nil, fmt.Errorf("recording %s: %w", req.URL.Redacted(), err)
+	}
+	return resp, nil
+}
+
+// replayTransport answers requests from a --record directory and never
+// touches the network. A request that wasn't recorded fails.
+type replayTransport struct {
+	dir     string
+	counter requestCounter
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	n := t.counter.next(req.Method, req.URL.String())
+	data, err := os.ReadFile(recordingFile(t.dir, req.Method, req.URL.String(), n))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded response to %s %s", req.Method, req.URL.Redacted())
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, fmt.Errorf("replaying %s: %w", req.URL.Redacted(), err)
+	}
+	resp.Header.Del(recordedRequestHeader)
+	return resp, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, "%s response %d", r.URL.Path, calls)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	started := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+	if err := startRecording(dir, started, []string{"--feed", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	get := func(client *http.Client, path string) (int, string, error) {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), err
+	}
+
+	recorder := newHTTPClient(httpOptions{timeout: 5 * time.Second, recordDir: dir})
+	var recorded []string
+	for _, path := range []string{"/feed", "/feed", "/missing"} {
+		status, body, err := get(recorder, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded = append(recorded, fmt.Sprintf("%d %s", status, body))
+	}
+
+	server.Close()
+	replayed, err := recordedRunTime(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replayed.Equal(started) {
+		t.Errorf("recordedRunTime = %s, want %s", replayed, started)
+	}
+
+	player := newHTTPClient(httpOptions{timeout: 5 * time.Second, replayDir: dir})
+	for i, path := range []string{"/feed", "/feed", "/missing"} {
+		status, body, err := get(player, path)
+		if err != nil {
+			t.Fatalf("replaying %s: %v", path, err)
+		}
+		if got := fmt.Sprintf("%d %s", status, body); got != recorded[i] {
+			t.Errorf("replayed %s = %q, want %q", path, got, recorded[i])
+		}
+	}
+	// The feed was fetched twice when recording; a third fetch has no
+	// recording to answer it.
+	if _, _, err := get(player, "/feed"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("replaying an unrecorded request: err = %v", err)
+	}
+}
+
+func TestReplayHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "<rss/>")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if _, err := newHTTPClient(httpOptions{timeout: 5 * time.Second, recordDir: dir}).Get(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newHTTPClient(httpOptions{timeout: 5 * time.Second, replayDir: dir}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("ETag") != `"v1"` || resp.Header.Get("Content-Type") != "application/xml" {
+		t.Errorf("replayed headers = %v", resp.Header)
+	}
+	if resp.Header.Get(recordedRequestHeader) != "" {
+		t.Errorf("replayed response still carries %s", recordedRequestHeader)
+	}
+}
+
+func TestResolveSecretOffline(t *testing.T) {
+	secretsOffline = true
+	defer func() { secretsOffline = false }()
+	for _, value := range []string{"gcp-secret:projects/p/secrets/s/versions/1", "aws-ssm:/feeds/token"} {
+		if _, err := resolveSecret(value); err == nil {
+			t.Errorf("resolveSecret(%q) succeeded during a replay", value)
+		}
+	}
+	if got, err := resolveSecret(" plain "); err != nil || got != "plain" {
+		t.Errorf("resolveSecret(plain) = %q, %v", got, err)
+	}
+}
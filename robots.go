@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	robotsAgent    = "filtered-data-rss"
+	maxRobotsBytes = 512 << 10
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsCache fetches each host's robots.txt once and answers whether a URL
+// may be fetched. Hosts whose robots.txt is missing or unreachable are
+// treated as allowing everything, as most crawlers do.
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	once  sync.Once
+	rules []robotsRule
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{hosts: make(map[string]*robotsEntry)}
+}
+
+func (c *robotsCache) allowed(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.hosts[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		c.hosts[origin] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = fetchRobotsRules(origin + "/robots.txt")
+	})
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return robotsAllows(entry.rules, path)
+}
+
+func fetchRobotsRules(robotsURL string) []robotsRule {
+	resp, err := httpGet(robotsURL)
+	if err != nil {
+		debugf("robots.txt %s: %v", robotsURL, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	return parseRobots(io.LimitReader(resp.Body, maxRobotsBytes), robotsAgent)
+}
+
+// parseRobots returns the rules of the group that applies to agent: the
+// group naming it, or the "*" group if none does.
+func parseRobots(r io.Reader, agent string) []robotsRule {
+	var (
+		specific, wildcard []robotsRule
+		hasSpecific        bool
+		groupAgents        []string
+		inRules            bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			a := strings.ToLower(value)
+			groupAgents = append(groupAgents, a)
+			// A group naming the agent applies even if it has no rules.
+			if a != "" && a != "*" && strings.Contains(strings.ToLower(agent), a) {
+				hasSpecific = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// An empty Disallow allows everything; an empty Allow is a no-op.
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, a := range groupAgents {
+				switch {
+				case a == "*":
+					wildcard = append(wildcard, rule)
+				case a != "" && strings.Contains(strings.ToLower(agent), a):
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+	if hasSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// robotsAllows applies the longest matching rule, with Allow winning ties.
+func robotsAllows(rules []robotsRule, path string) bool {
+	allowed, best := true, -1
+	for _, rule := range rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			allowed, best = rule.allow, n
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches path against a robots.txt pattern, where "*" matches
+// any sequence of characters and a trailing "$" anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored && rest != "" {
+		// The last part may occur again later in the path.
+		last := parts[len(parts)-1]
+		return len(parts) > 1 && strings.HasSuffix(path, last)
+	}
+	return true
+}
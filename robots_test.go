@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private/page", true},
+		{"/private", "/public", false},
+		{"/*.pdf", "/docs/file.pdf", true},
+		{"/*.pdf$", "/docs/file.pdf", true},
+		{"/*.pdf$", "/docs/file.pdf?download=1", false},
+		{"/*.pdf$", "/a.pdf/b.pdf", true},
+		{"/page$", "/page", true},
+		{"/page$", "/page/2", false},
+		{"/*/edit", "/posts/1/edit", true},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name, robots string
+		allowed      map[string]bool
+	}{
+		{
+			name: "wildcard group",
+			robots: `User-agent: *
+Disallow: /private
+Allow: /private/open`,
+			allowed: map[string]bool{"/": true, "/private/x": false, "/private/open/x": true},
+		},
+		{
+			name: "specific group wins",
+			robots: `User-agent: *
+Disallow: /
+
+User-agent: Filtered-Data-RSS
+Disallow: /drafts # not yet`,
+			allowed: map[string]bool{"/blog": true, "/drafts/1": false},
+		},
+		{
+			name: "empty specific group allows everything",
+			robots: `User-agent: *
+Disallow: /
+
+User-agent: filtered-data-rss
+Disallow:`,
+			allowed: map[string]bool{"/blog": true},
+		},
+		{
+			name: "shared group",
+			robots: `User-agent: otherbot
+User-agent: filtered-data-rss
+Disallow: /tmp`,
+			allowed: map[string]bool{"/tmp/x": false, "/blog": true},
+		},
+		{
+			name: "other agents only",
+			robots: `User-agent: otherbot
+Disallow: /`,
+			allowed: map[string]bool{"/": true},
+		},
+		{
+			name: "longest match, allow wins ties",
+			robots: `User-agent: *
+Disallow: /a
+Allow: /a/b
+Disallow: /a/b/c
+Allow: /x
+Disallow: /x`,
+			allowed: map[string]bool{"/a/1": false, "/a/b/1": true, "/a/b/c/1": false, "/x": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(tt.robots), robotsAgent)
+			for path, want := range tt.allowed {
+				if got := robotsAllows(rules, path); got != want {
+					t.Errorf("robotsAllows(%q) = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRobotsCache(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches++
+			w.Write([]byte("User-agent: *\nDisallow: /private\nDisallow: /*?print=1$\n"))
+		}
+	}))
+	defer server.Close()
+
+	cache := newRobotsCache()
+	tests := map[string]bool{
+		server.URL + "/post":         true,
+		server.URL + "/private/post": false,
+		server.URL + "/post?print=1": false,
+		server.URL:                   true,
+		"not a url":                  false,
+	}
+	for u, want := range tests {
+		if got := cache.allowed(u); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", u, got, want)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("robots.txt fetched %d times, want once", fetches)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	urlAttributePattern    = regexp.MustCompile(`(?i)(\b(?:href|src|srcset|action|formaction|poster|xlink:href)\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+	xmlURLAttributePattern = regexp.MustCompile(`(\b(?:url|href)\s*=\s*)("[^"]*"|'[^']*')`)
+	schemeNoise            = regexp.MustCompile(`[\x00-\x20]+`)
+)
+
+// isDangerousURL reports whether u uses a scheme that executes code or
+// embeds arbitrary content when a reader renders it. Browsers ignore
+// whitespace, control characters and entity-encoding inside the scheme, so
+// they're removed before checking.
+func isDangerousURL(u string) bool {
+	u = schemeNoise.ReplaceAllString(html.UnescapeString(u), "")
+	u = strings.ToLower(u)
+	return strings.HasPrefix(u, "javascript:") ||
+		strings.HasPrefix(u, "vbscript:") ||
+		strings.HasPrefix(u, "data:")
+}
+
+// sanitizeLinks drops item links and permalink GUIDs with dangerous schemes,
+// as well as passed-through elements (enclosures, media:content,
+// media:thumbnail, source, ...) whose url or href is one. Such URLs inside the
+// HTML of the description and content are rewritten to "#".
+func sanitizeLinks(item *Item) {
+	if isDangerousURL(item.Link) {
+		item.Link = ""
+	}
+	if item.GUID.permaLink() && isDangerousURL(item.GUID.Value) {
+		item.GUID = GUID{}
+	}
+	item.Description = sanitizeHTMLURLs(item.Description)
+	item.Content = sanitizeHTMLURLs(item.Content)
+	item.Extra = sanitizeExtraURLs(item.Extra)
+}
+
+// sanitizeExtraURLs drops the elements whose url or href attribute is
+// dangerous, and blanks such attributes in their children, like the
+// media:content elements of a media:group.
+func sanitizeExtraURLs(elements []RawElement) []RawElement {
+	var kept []RawElement
+	for _, e := range elements {
+		dangerous := false
+		for _, a := range e.Attrs {
+			if (a.Name.Local == "url" || a.Name.Local == "href") && isDangerousURL(a.Value) {
+				dangerous = true
+			}
+		}
+		if dangerous {
+			continue
+		}
+		e.Inner = xmlURLAttributePattern.ReplaceAllStringFunc(e.Inner, func(attr string) string {
+			m := xmlURLAttributePattern.FindStringSubmatch(attr)
+			if !isDangerousURL(m[2][1 : len(m[2])-1]) {
+				return attr
+			}
+			return m[1] + `""`
+		})
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+func sanitizeHTMLURLs(s string) string {
+	if s == "" {
+		return s
+	}
+	return urlAttributePattern.ReplaceAllStringFunc(s, func(attr string) string {
+		m := urlAttributePattern.FindStringSubmatch(attr)
+		if !isDangerousURL(strings.Trim(m[2], `"'`)) {
+			return attr
+		}
+		return m[1] + `"#"`
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestIsDangerousURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/", false},
+		{"/relative/path", false},
+		{"mailto:someone@example.com", false},
+		{"javascript:alert(1)", true},
+		{"JavaScript:alert(1)", true},
+		{" java\tscript:alert(1)", true},
+		{"jav&#x09;ascript:alert(1)", true},
+		{"&#106;avascript:alert(1)", true},
+		{"vbscript:msgbox", true},
+		{"data:text/html;base64,PHNjcmlwdD4=", true},
+	}
+	for _, tt := range tests {
+		if got := isDangerousURL(tt.url); got != tt.want {
+			t.Errorf("isDangerousURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeHTMLURLs(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`<a href="https://example.com">ok</a>`, `<a href="https://example.com">ok</a>`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a href="#">x</a>`},
+		{`<a HREF='JAVASCRIPT:alert(1)'>x</a>`, `<a HREF="#">x</a>`},
+		{`<img src=data:image/svg+xml,evil>`, `<img src="#">`},
+		{`<form action = "vbscript:x">`, `<form action = "#">`},
+		{`<p>javascript: is just text here</p>`, `<p>javascript: is just text here</p>`},
+	}
+	for _, tt := range tests {
+		if got := sanitizeHTMLURLs(tt.in); got != tt.want {
+			t.Errorf("sanitizeHTMLURLs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeLinks(t *testing.T) {
+	item := Item{
+		Link:        "javascript:alert(1)",
+		Description: `<a href="javascript:x">d</a>`,
+		Content:     `<a href="https://example.com">c</a>`,
+	}
+	sanitizeLinks(&item)
+	if item.Link != "" {
+		t.Errorf("link = %q, want it dropped", item.Link)
+	}
+	if item.Description != `<a href="#">d</a>` {
+		t.Errorf("description = %q", item.Description)
+	}
+	if item.Content != `<a href="https://example.com">c</a>` {
+		t.Errorf("content = %q", item.Content)
+	}
+}
+
+func TestSanitizeLinksGUID(t *testing.T) {
+	tests := []struct {
+		guid GUID
+		want GUID
+	}{
+		{GUID{Value: "javascript:alert(1)"}, GUID{}},
+		{GUID{Value: "data:text/html,evil", IsPermaLink: "true"}, GUID{}},
+		{GUID{Value: "https://example.com/1"}, GUID{Value: "https://example.com/1"}},
+		// Not a link, so readers don't open it.
+		{GUID{Value: "javascript:1", IsPermaLink: "false"}, GUID{Value: "javascript:1", IsPermaLink: "false"}},
+	}
+	for _, tt := range tests {
+		item := Item{Title: "Post", GUID: tt.guid}
+		sanitizeLinks(&item)
+		if item.GUID != tt.want {
+			t.Errorf("GUID %+v sanitized to %+v, want %+v", tt.guid, item.GUID, tt.want)
+		}
+	}
+}
+
+func TestSanitizeLinksExtra(t *testing.T) {
+	element := func(space, local, attr, url, inner string) RawElement {
+		return RawElement{
+			Name:  xml.Name{Space: space, Local: local},
+			Attrs: []xml.Attr{{Name: xml.Name{Local: attr}, Value: url}},
+			Inner: inner,
+		}
+	}
+	tests := []struct {
+		name string
+		in   RawElement
+		want []RawElement
+	}{
+		{"enclosure", element("", "enclosure", "url", "javascript:alert(1)", ""), nil},
+		{"media:content", element(mediaNamespace, "content", "url", "data:image/svg+xml,evil", ""), nil},
+		{"media:thumbnail", element(mediaNamespace, "thumbnail", "url", " JaVaScRiPt:alert(1)", ""), nil},
+		{"source", element("", "source", "url", "vbscript:x", "Blog"), nil},
+		{"atom:link", element(atomNamespace, "link", "href", "javascript:x", ""), nil},
+		{
+			"safe enclosure",
+			element("", "enclosure", "url", "https://example.com/a.mp3", ""),
+			[]RawElement{element("", "enclosure", "url", "https://example.com/a.mp3", "")},
+		},
+		{
+			"media:group children",
+			element(mediaNamespace, "group", "id", "1", `<content url="data:image/png;base64,AAAA"></content><content url='https://example.com/a.png'></content>`),
+			[]RawElement{element(mediaNamespace, "group", "id", "1", `<content url=""></content><content url='https://example.com/a.png'></content>`)},
+		},
+	}
+	for _, tt := range tests {
+		item := Item{Title: "Post", Extra: []RawElement{tt.in}}
+		sanitizeLinks(&item)
+		if !reflect.DeepEqual(item.Extra, tt.want) {
+			t.Errorf("%s: extra = %+v, want %+v", tt.name, item.Extra, tt.want)
+		}
+	}
+}
+
+func TestCleanItemsDropsDangerousThumbnail(t *testing.T) {
+	// As enrichOpenGraph adds it for a page whose og:image is a data: URL.
+	item := Item{Title: "Post", Link: "https://example.com/1", Extra: []RawElement{{
+		Name:  xml.Name{Space: mediaNamespace, Local: "thumbnail"},
+		Attrs: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: "data:image/svg+xml,<svg onload=alert(1)>"}},
+	}}}
+	cleaned := cleanItems([]Item{item})
+	if image := cleaned[0].image(); image != "" {
+		t.Errorf("image = %q, want none", image)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scoreConfig is the JSON file given to --score-config:
+//
+//	{
+//	  "keywords": {"llm": 3, "dbt": 2, "webinar": -5},
+//	  "fields": {"title": 2, "categories": 1.5, "content": 1}
+//	}
+//
+// An item scores each keyword's weight, times the field's weight, for every
+// field the keyword occurs in. Fields default to title 2, categories 1.5 and
+// content 1.
+type scoreConfig struct {
+	Keywords map[string]float64 `json:"keywords"`
+	Fields   map[string]float64 `json:"fields"`
+}
+
+var defaultScoreFields = map[string]float64{
+	"title":      2,
+	"categories": 1.5,
+	"content":    1,
+}
+
+type scoreTerm struct {
+	matcher *keywordMatcher
+	weight  float64
+}
+
+type scorer struct {
+	terms  []scoreTerm
+	fields map[string]float64
+}
+
+// describe lists the scorer's weights in a stable order.
+func (s *scorer) describe() string {
+	var parts []string
+	for _, term := range s.terms {
+		parts = append(parts, fmt.Sprintf("%s=%v", term.matcher, term.weight))
+	}
+	fields := make([]string, 0, len(s.fields))
+	for field := range s.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s:%v", field, s.fields[field]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+func loadScorer(path string) (*scorer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read score config: %w", err)
+	}
+	var config scoreConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse score config: %w", err)
+	}
+	if len(config.Keywords) == 0 {
+		return nil, errors.New("score config has no keywords")
+	}
+
+	s := &scorer{fields: make(map[string]float64)}
+	for field, weight := range defaultScoreFields {
+		s.fields[field] = weight
+	}
+	for field, weight := range config.Fields {
+		if _, ok := defaultScoreFields[field]; !ok {
+			return nil, fmt.Errorf("unknown score field %q (want title, categories or content)", field)
+		}
+		s.fields[field] = weight
+	}
+
+	keywords := make([]string, 0, len(config.Keywords))
+	for keyword := range config.Keywords {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		trimmed := strings.TrimSpace(keyword)
+		if trimmed == "" {
+			continue
+		}
+		// Match whole words only, so "ai" doesn't score "maintain".
+		s.terms = append(s.terms, scoreTerm{matcher: newKeywordMatcher(trimmed), weight: config.Keywords[keyword]})
+	}
+	return s, nil
+}
+
+// score returns the item's relevance. Each keyword counts once per field,
+// however often it's repeated, so long posts don't win on length alone.
+func (s *scorer) score(item Item) float64 {
+	fields := map[string]string{
+		"title":      item.Title,
+		"categories": strings.Join(item.Categories, "\n"),
+		"content":    html.UnescapeString(htmlTagPattern.ReplaceAllString(item.Description+"\n"+item.Content, " ")),
+	}
+	total := 0.0
+	for _, term := range s.terms {
+		for field, text := range fields {
+			if term.matcher.match(text) {
+				total += term.weight * s.fields[field]
+			}
+		}
+	}
+	return total
+}
+
+// sortItemsByScore orders items from highest to lowest score, newest first
+// among equal scores.
+func sortItemsByScore(items []Item, s *scorer, undatedFirst bool) {
+	sortItemsByDate(items, undatedFirst)
+	scores := make([]float64, len(items))
+	for i, item := range items {
+		scores[i] = s.score(item)
+	}
+	sort.Stable(byScore{items: items, scores: scores})
+}
+
+type byScore struct {
+	items  []Item
+	scores []float64
+}
+
+func (b byScore) Len() int           { return len(b.items) }
+func (b byScore) Less(i, j int) bool { return b.scores[i] > b.scores[j] }
+func (b byScore) Swap(i, j int) {
+	b.items[i], b.items[j] = b.items[j], b.items[i]
+	b.scores[i], b.scores[j] = b.scores[j], b.scores[i]
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeScoreConfig(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "score.json")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScorer(t *testing.T) {
+	s, err := loadScorer(writeScoreConfig(t, `{"keywords": {"llm": 3, "dbt": 2, "webinar": -5, " ": 9}, "fields": {"title": 4}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		item Item
+		want float64
+	}{
+		{"title", Item{Title: "LLM evals"}, 12},
+		{"title and content", Item{Title: "LLM evals", Description: "<p>More on llm &amp; dbt</p>"}, 17},
+		{"repeats count once per field", Item{Description: "llm llm llm"}, 3},
+		{"categories", Item{Categories: []string{"dbt"}}, 3},
+		{"negative", Item{Title: "Webinar: dbt"}, -12},
+		{"whole words", Item{Title: "dbtx allm"}, 0},
+	}
+	for _, tt := range tests {
+		if got := s.score(tt.item); got != tt.want {
+			t.Errorf("%s: score = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadScorerErrors(t *testing.T) {
+	for _, config := range []string{`{}`, `{"keywords": {"a": 1}, "fields": {"body": 1}}`, `not json`} {
+		if _, err := loadScorer(writeScoreConfig(t, config)); err == nil {
+			t.Errorf("loadScorer accepted %s", config)
+		}
+	}
+}
+
+func TestSortItemsByScore(t *testing.T) {
+	s, err := loadScorer(writeScoreConfig(t, `{"keywords": {"go": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []Item{
+		{GUID: GUID{Value: "old match"}, Title: "Go", PubDate: "Mon, 05 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "no match"}, Title: "Rust", PubDate: "Tue, 13 Oct 2026 10:00:00 +0000"},
+		{GUID: GUID{Value: "new match"}, Title: "Go", PubDate: "Mon, 12 Oct 2026 10:00:00 +0000"},
+	}
+	sortItems(items, "score", false, s)
+	if got := itemGUIDs(items); got[0] != "new match" || got[1] != "old match" || got[2] != "no match" {
+		t.Errorf("order = %q, want matches first, newest first", got)
+	}
+}
+
+func TestMinScoreFilter(t *testing.T) {
+	flags := filterFlags{authorMatch: "any", minScore: "3", scoreConfig: writeScoreConfig(t, `{"keywords": {"go": 1}}`)}
+	filters, err := flags.buildFilters(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept, _ := applyFilters([]Item{{Title: "Go"}, {Title: "Go", Categories: []string{"go"}}}, filters); len(kept) != 1 {
+		t.Errorf("kept %d items, want only the one scoring 3.5", len(kept))
+	}
+	for _, flags := range []filterFlags{
+		{authorMatch: "any", minScore: "3"},
+		{authorMatch: "any", minScore: "high", scoreConfig: flags.scoreConfig},
+	} {
+		if _, err := flags.buildFilters(time.Time{}); err == nil {
+			t.Errorf("buildFilters accepted %+v", flags)
+		}
+	}
+}
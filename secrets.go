@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// secretClient talks to metadata servers and secret managers. It is separate
+// from httpClient because those endpoints are configured by the operator, not
+// taken from feeds, and some of them live on link-local addresses that
+// --block-private-addresses would refuse.
+var secretClient = &http.Client{Timeout: 10 * time.Second}
+
+// secretsOffline refuses secret manager references, which would need the
+// network, during --replay.
+var secretsOffline bool
+
+// getenvSecret returns the secret named by the environment variable name,
+// reading it from the file in name_FILE when that is set instead. Either way,
+// the value may be a secret manager reference (see resolveSecret).
+func getenvSecret(name string) (string, error) {
+	value := os.Getenv(name)
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading %s_FILE: %w", name, err)
+		}
+		value = string(data)
+	}
+	return resolveSecret(value)
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return resolveSecret(string(data))
+}
+
+// resolveSecret fetches values of the form
+//
+//	gcp-secret:projects/<project>/secrets/<name>/versions/<version>
+//	aws-ssm:<parameter name>
+//
+// from GCP Secret Manager or AWS SSM Parameter Store. Other values are
+// returned with surrounding whitespace trimmed.
+func resolveSecret(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if secretsOffline && (strings.HasPrefix(value, "gcp-secret:") || strings.HasPrefix(value, "aws-ssm:")) {
+		prefix, _, _ := strings.Cut(value, ":")
+		return "", fmt.Errorf("%s: references can't be resolved during --replay; set the secret's value instead", prefix)
+	}
+	switch {
+	case strings.HasPrefix(value, "gcp-secret:"):
+		return fetchGCPSecret(strings.TrimPrefix(value, "gcp-secret:"))
+	case strings.HasPrefix(value, "aws-ssm:"):
+		return fetchSSMParameter(strings.TrimPrefix(value, "aws-ssm:"))
+	}
+	return value, nil
+}
+
+func fetchGCPSecret(name string) (string, error) {
+	token, err := gcpAccessToken()
+	if err != nil {
+		return "", fmt.Errorf("getting GCP access token: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://secretmanager.googleapis.com/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var response struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := doJSON(req, &response); err != nil {
+		return "", fmt.Errorf("accessing secret %s: %w", name, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(response.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decoding secret %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// gcpAccessToken uses GOOGLE_OAUTH_ACCESS_TOKEN when set, and the metadata
+// server of the GCE instance, Cloud Run service or GKE pod otherwise.
+func gcpAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &response); err != nil {
+		return "", err
+	}
+	return response.AccessToken, nil
+}
+
+// fetchSSMParameter calls SSM GetParameter with decryption, signing the
+// request with the credentials in the standard AWS_* environment variables.
+func fetchSSMParameter(name string) (string, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if accessKey == "" || secretKey == "" || region == "" {
+		return "", errors.New("aws-ssm secrets need AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_REGION")
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"Name": name, "WithDecryption": true})
+	host := "ssm." + region + ".amazonaws.com"
+	req, err := http.NewRequest(http.MethodPost, "https://"+host+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonSSM.GetParameter")
+	signAWSRequest(req, payload, host, region, "ssm", accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), time.Now().UTC())
+
+	var response struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	if err := doJSON(req, &response); err != nil {
+		return "", fmt.Errorf("getting SSM parameter %s: %w", name, err)
+	}
+	return strings.TrimSpace(response.Parameter.Value), nil
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers to a request whose
+// path is "/" and that has no query string, which is all SSM needs.
+func signAWSRequest(req *http.Request, payload []byte, host, region, service, accessKey, secretKey, sessionToken string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := [][2]string{
+		{"content-type", req.Header.Get("Content-Type")},
+		{"host", host},
+		{"x-amz-date", amzDate},
+	}
+	if sessionToken != "" {
+		headers = append(headers, [2]string{"x-amz-security-token", sessionToken})
+	}
+	headers = append(headers, [2]string{"x-amz-target", req.Header.Get("X-Amz-Target")})
+
+	var canonicalHeaders strings.Builder
+	var signedHeaders []string
+	for _, header := range headers {
+		canonicalHeaders.WriteString(header[0] + ":" + strings.TrimSpace(header[1]) + "\n")
+		signedHeaders = append(signedHeaders, header[0])
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func doJSON(req *http.Request, v interface{}) error {
+	resp, err := secretClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
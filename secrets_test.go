@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetenvSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, value, file, want string
+	}{
+		{"plain", " from-env ", "", "from-env"},
+		{"file wins", "from-env", path, "from-file"},
+		{"unset", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECRET", tt.value)
+			t.Setenv("TEST_SECRET_FILE", tt.file)
+			got, err := getenvSecret("TEST_SECRET")
+			if err != nil || got != tt.want {
+				t.Errorf("getenvSecret = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := getenvSecret("TEST_SECRET"); err == nil {
+		t.Error("getenvSecret ignored a missing _FILE")
+	}
+}
+
+type secretTransport func(*http.Request) *http.Response
+
+func (f secretTransport) RoundTrip(req *http.Request) (*http.Response, error) { return f(req), nil }
+
+func TestResolveGCPSecret(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "gcp-token")
+	defer func(client *http.Client) { secretClient = client }(secretClient)
+	secretClient = &http.Client{Transport: secretTransport(func(req *http.Request) *http.Response {
+		if req.URL.String() != "https://secretmanager.googleapis.com/v1/projects/p/secrets/s/versions/1:access" ||
+			req.Header.Get("Authorization") != "Bearer gcp-token" {
+			return &http.Response{StatusCode: http.StatusForbidden, Body: io.NopCloser(strings.NewReader("denied"))}
+		}
+		body := `{"payload": {"data": "` + base64.StdEncoding.EncodeToString([]byte("s3cret\n")) + `"}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+	})}
+
+	got, err := resolveSecret("gcp-secret:projects/p/secrets/s/versions/1")
+	if err != nil || got != "s3cret" {
+		t.Errorf("resolveSecret = %q, %v; want s3cret", got, err)
+	}
+	if _, err := resolveSecret("gcp-secret:projects/p/secrets/other/versions/1"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("resolveSecret of a denied secret = %v, want a 403 error", err)
+	}
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	payload := []byte(`{"Name":"/app/token","WithDecryption":true}`)
+	host := "ssm.eu-west-1.amazonaws.com"
+	req, _ := http.NewRequest(http.MethodPost, "https://"+host+"/", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonSSM.GetParameter")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	signAWSRequest(req, payload, host, "eu-west-1", "ssm", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "", now)
+
+	if got := req.Header.Get("X-Amz-Date"); got != "20261016T120000Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+	// Computed independently from the Signature Version 4 specification.
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261016/eu-west-1/ssm/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date;x-amz-target, " +
+		"Signature=4ce1bff264651d42637235b4f13a58bcd32053f4fe101cfcb95e1d3e07e1480b"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// systemdListenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service.
+const systemdListenFDsStart = 3
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "localhost:8080", "Where to listen: 'host:port', 'unix:/path/to.sock', or 'systemd' for a socket passed by systemd socket activation")
+	feedURL := fs.String("feed", "", "RSS feed URL or file to filter")
+	format := fs.String("format", "rss", "Output format: 'rss' or 'markdown'")
+	refresh := fs.Duration("refresh", 15*time.Minute, "How long a fetched feed is served before it's fetched again")
+	sortOrder := fs.String("sort", "date-desc", "Output order: 'date-desc', 'date-asc', 'title', 'source', or 'score'")
+	limit := fs.Int("limit", 0, "Maximum number of items to serve (0 = no limit)")
+	var filterOpts filterFlags
+	filterOpts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *feedURL == "" {
+		fmt.Fprintf(os.Stderr, "Error: --feed is required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != "rss" && *format != "markdown" {
+		fatalf("Error: --format must be 'rss' or 'markdown'")
+	}
+	if !validSortOrders[*sortOrder] {
+		fatalf("Error: --sort must be 'date-desc', 'date-asc', 'title', 'source', or 'score'")
+	}
+	if filterOpts.since == "last-run" {
+		fatalf("Error: --since last-run is not supported by serve")
+	}
+	// Check the filter options once up front, so mistakes fail at startup
+	// rather than on the first request.
+	if _, err := parseSince(filterOpts.since, time.Now(), runState{}); err != nil {
+		fatalf("Error: --since: %v", err)
+	}
+	if _, err := filterOpts.buildFilters(time.Time{}); err != nil {
+		fatalf("Error: %v", err)
+	}
+	scoring, err := filterOpts.scorer()
+	if err != nil {
+		fatalf("Error loading score config: %v", err)
+	}
+	if *sortOrder == "score" && scoring == nil {
+		fatalf("Error: --sort score requires --score-config")
+	}
+
+	feed := &servedFeed{refresh: *refresh, render: func() ([]byte, error) {
+		now := time.Now()
+		cutoff, _ := parseSince(filterOpts.since, now, runState{})
+		filters, err := filterOpts.buildFilters(cutoff)
+		if err != nil {
+			return nil, err
+		}
+		items, err := loadFeedItems(*feedURL)
+		if err != nil {
+			return nil, err
+		}
+		synthesizeGUIDs(items)
+		items, _ = applyFilters(items, filters)
+		sortItems(items, *sortOrder, false, scoring)
+		items = limitItems(items, *limit)
+
+		var buf bytes.Buffer
+		if *format == "markdown" {
+			outputMarkdown(&buf, items, markdownOptions{})
+		} else {
+			outputRSS(&buf, items, Channel{}, provenance{generated: now, sources: []string{*feedURL}, filters: filters})
+		}
+		debugf("Rendered %d items in %s", len(items), time.Since(now).Round(time.Millisecond))
+		return buf.Bytes(), nil
+	}}
+	contentType := "application/rss+xml; charset=utf-8"
+	if *format == "markdown" {
+		contentType = "text/markdown; charset=utf-8"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		body, generated, err := feed.get()
+		if err != nil {
+			http.Error(w, "fetching feed failed", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Last-Modified", generated.UTC().Format(http.TimeFormat))
+		w.Write(body)
+	})
+
+	listener, err := serveListener(*listen)
+	if err != nil {
+		fatalf("Error: --listen: %v", err)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	infof("Serving %s on %s", *feedURL, listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalf("Error serving: %v", err)
+	}
+}
+
+// servedFeed caches the rendered feed for refresh. One request at a time
+// fetches the feed again, without holding up the others, which keep getting
+// the previous rendering meanwhile. When fetching fails, the previous
+// rendering keeps being served until the next refresh is due.
+type servedFeed struct {
+	refresh time.Duration
+	render  func() ([]byte, error)
+
+	mu        sync.Mutex
+	body      []byte
+	generated time.Time
+	// retryAt is when to fetch again after a failed refresh.
+	retryAt time.Time
+	// fetching is closed when the fetch in flight, if any, is done.
+	fetching chan struct{}
+	err      error
+}
+
+func (f *servedFeed) get() ([]byte, time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.body == nil && f.fetching != nil {
+		// Nothing to serve yet: wait for the first fetch.
+		done := f.fetching
+		f.mu.Unlock()
+		<-done
+		f.mu.Lock()
+		if f.body == nil {
+			return nil, time.Time{}, f.err
+		}
+	}
+	now := time.Now()
+	if f.body != nil && (f.fetching != nil || now.Sub(f.generated) < f.refresh || now.Before(f.retryAt)) {
+		return f.body, f.generated, nil
+	}
+
+	done := make(chan struct{})
+	f.fetching = done
+	f.mu.Unlock()
+	body, err := f.render()
+	f.mu.Lock()
+	f.fetching = nil
+	close(done)
+
+	f.err = err
+	if err != nil {
+		if f.body != nil {
+			warnf("Refreshing feed: %v; serving the copy from %s", err, f.generated.Format(time.RFC3339))
+			f.retryAt = time.Now().Add(f.refresh)
+			return f.body, f.generated, nil
+		}
+		warnf("Fetching feed: %v", err)
+		return nil, time.Time{}, err
+	}
+	f.body, f.generated = body, time.Now()
+	return f.body, f.generated, nil
+}
+
+// serveListener opens the listener described by spec: a TCP address, a
+// Unix socket ("unix:/run/feed.sock"), or "systemd" for the socket systemd
+// passes in with socket activation (LISTEN_FDS).
+func serveListener(spec string) (net.Listener, error) {
+	switch {
+	case spec == "systemd":
+		return systemdListener()
+	case strings.HasPrefix(spec, "unix:"):
+		path := strings.TrimPrefix(spec, "unix:")
+		if path == "" {
+			return nil, errors.New("unix: needs a socket path")
+		}
+		// A socket left behind by a previous run that didn't shut down
+		// cleanly would make Listen fail.
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		// Let a reverse proxy in the same group connect.
+		if err := os.Chmod(path, 0660); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	}
+	return net.Listen("tcp", spec)
+}
+
+func systemdListener() (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("no socket passed by systemd (LISTEN_PID isn't this process)")
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, errors.New("no socket passed by systemd (LISTEN_FDS)")
+	}
+	if fds > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets; configure exactly one", fds)
+	}
+	// The variables are meant for this process only, not its children.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(systemdListenFDsStart, "systemd-socket")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("using the socket passed by systemd: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServedFeed(t *testing.T) {
+	renders := 0
+	var failure error
+	feed := &servedFeed{refresh: time.Hour, render: func() ([]byte, error) {
+		renders++
+		if failure != nil {
+			return nil, failure
+		}
+		return []byte("feed " + strconv.Itoa(renders)), nil
+	}}
+
+	failure = errors.New("unreachable")
+	captureOutput(t, &os.Stderr, func() {
+		if _, _, err := feed.get(); err == nil {
+			t.Error("get without a cached copy hid the fetch error")
+		}
+	})
+
+	failure = nil
+	body, _, err := feed.get()
+	if err != nil || string(body) != "feed 2" {
+		t.Fatalf("get = %q, %v; want feed 2", body, err)
+	}
+	if body, _, _ := feed.get(); string(body) != "feed 2" || renders != 2 {
+		t.Errorf("get within the refresh interval rendered again: %q after %d renders", body, renders)
+	}
+
+	// Expired, and the refresh fails: the previous copy is served.
+	feed.refresh = 0
+	failure = errors.New("unreachable")
+	captureOutput(t, &os.Stderr, func() {
+		if body, _, err := feed.get(); err != nil || string(body) != "feed 2" {
+			t.Errorf("get after a failed refresh = %q, %v; want the cached copy", body, err)
+		}
+	})
+	failure = nil
+	if body, _, _ := feed.get(); string(body) != "feed 4" {
+		t.Errorf("get after expiry = %q, want feed 4", body)
+	}
+}
+
+func TestServedFeedBacksOffAfterFailedRefresh(t *testing.T) {
+	renders := 0
+	feed := &servedFeed{refresh: time.Hour, render: func() ([]byte, error) {
+		renders++
+		return nil, errors.New("unreachable")
+	}}
+	feed.body, feed.generated = []byte("stale"), time.Now().Add(-2*time.Hour)
+
+	captureOutput(t, &os.Stderr, func() {
+		for i := 0; i < 3; i++ {
+			if body, _, err := feed.get(); err != nil || string(body) != "stale" {
+				t.Errorf("get = %q, %v; want the stale copy", body, err)
+			}
+		}
+	})
+	if renders != 1 {
+		t.Errorf("feed fetched %d times, want once until the next refresh is due", renders)
+	}
+}
+
+func TestServedFeedSingleFetch(t *testing.T) {
+	started, release := make(chan bool), make(chan bool)
+	var renders atomic.Int32
+	feed := &servedFeed{refresh: time.Hour, render: func() ([]byte, error) {
+		renders.Add(1)
+		started <- true
+		<-release
+		return []byte("fresh"), nil
+	}}
+	feed.body, feed.generated = []byte("stale"), time.Now().Add(-2*time.Hour)
+
+	refreshed := make(chan []byte)
+	go func() {
+		body, _, _ := feed.get()
+		refreshed <- body
+	}()
+	<-started
+	// Others don't wait for the refresh in flight, nor start another.
+	for i := 0; i < 3; i++ {
+		if body, _, _ := feed.get(); string(body) != "stale" {
+			t.Errorf("get during a refresh = %q, want the stale copy", body)
+		}
+	}
+	release <- true
+	if body := <-refreshed; string(body) != "fresh" {
+		t.Errorf("refreshing get = %q, want fresh", body)
+	}
+	if body, _, _ := feed.get(); string(body) != "fresh" {
+		t.Errorf("get after the refresh = %q, want fresh", body)
+	}
+
+	// Without a copy, requests wait for the first fetch and share it.
+	feed = &servedFeed{refresh: time.Hour, render: feed.render}
+	results := make(chan []byte, 2)
+	go func() {
+		body, _, _ := feed.get()
+		results <- body
+	}()
+	<-started
+	go func() {
+		body, _, _ := feed.get()
+		results <- body
+	}()
+	release <- true
+	for i := 0; i < 2; i++ {
+		if body := <-results; string(body) != "fresh" {
+			t.Errorf("first get = %q, want fresh", body)
+		}
+	}
+	if n := renders.Load(); n != 2 {
+		t.Errorf("feed fetched %d times, want 2", n)
+	}
+}
+
+func TestServeListenerUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.sock")
+
+	// A socket left behind by a previous run.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skip("no Unix sockets:", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := serveListener("unix:" + path)
+	if err != nil {
+		t.Fatalf("serveListener with a stale socket: %v", err)
+	}
+	defer listener.Close()
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0660 {
+		t.Errorf("socket mode = %v, want 0660", info.Mode().Perm())
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://feed/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+		t.Errorf("response over the socket = %q, want ok", body)
+	}
+
+	if _, err := serveListener("unix:"); err == nil {
+		t.Error("serveListener accepted unix: without a path")
+	}
+	// Only sockets are removed, never a regular file in the way.
+	file := filepath.Join(t.TempDir(), "not-a-socket")
+	os.WriteFile(file, []byte("keep"), 0644)
+	if _, err := serveListener("unix:" + file); err == nil {
+		t.Error("serveListener replaced a regular file")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "keep" {
+		t.Error("serveListener removed a regular file")
+	}
+}
+
+func TestSystemdListenerErrors(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		listenPID, listenFDs, want string
+	}{
+		{"", "1", "LISTEN_PID"},
+		{"1", "1", "LISTEN_PID"},
+		{pid, "", "LISTEN_FDS"},
+		{pid, "0", "LISTEN_FDS"},
+		{pid, "2", "exactly one"},
+	}
+	for _, tt := range tests {
+		t.Setenv("LISTEN_PID", tt.listenPID)
+		t.Setenv("LISTEN_FDS", tt.listenFDs)
+		_, err := serveListener("systemd")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LISTEN_PID=%q LISTEN_FDS=%q: error %v, want one mentioning %s", tt.listenPID, tt.listenFDs, err, tt.want)
+		}
+	}
+}
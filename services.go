@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxServiceResponseBytes = 64 << 20
+	defaultFeedbinURL       = "https://api.feedbin.com/v2/"
+)
+
+// feedService is an RSS reader account used as the source of items
+// (--source-api): its unread items are filtered instead of a single feed.
+type feedService interface {
+	// unread returns up to limit (0 = all) unread items, newest first, and
+	// the service's id for each item.
+	unread(limit int) ([]Item, []string, error)
+	markRead(ids []string) error
+}
+
+func newFeedService(kind, endpoint string) (feedService, error) {
+	switch kind {
+	case "fever":
+		if endpoint == "" {
+			return nil, errors.New("--source-api fever requires --feed with the Fever API URL")
+		}
+		apiKey, err := getenvSecret("FEVER_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
+			username, err := getenvSecret("FEVER_USERNAME")
+			if err != nil {
+				return nil, err
+			}
+			password, err := getenvSecret("FEVER_PASSWORD")
+			if err != nil {
+				return nil, err
+			}
+			if username == "" || password == "" {
+				return nil, errors.New("fever requires FEVER_API_KEY, or FEVER_USERNAME and FEVER_PASSWORD")
+			}
+			sum := md5.Sum([]byte(username + ":" + password))
+			apiKey = hex.EncodeToString(sum[:])
+		}
+		return &feverService{endpoint: endpoint, apiKey: apiKey}, nil
+	case "feedbin":
+		if endpoint == "" {
+			endpoint = defaultFeedbinURL
+		}
+		username, err := getenvSecret("FEEDBIN_USERNAME")
+		if err != nil {
+			return nil, err
+		}
+		password, err := getenvSecret("FEEDBIN_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		if username == "" || password == "" {
+			return nil, errors.New("feedbin requires FEEDBIN_USERNAME and FEEDBIN_PASSWORD")
+		}
+		return &feedbinService{endpoint: strings.TrimRight(endpoint, "/") + "/", username: username, password: password}, nil
+	}
+	return nil, fmt.Errorf("unknown source API %q (want 'fever' or 'feedbin')", kind)
+}
+
+// serviceRequest sends req and decodes the JSON response into v, if v isn't
+// nil.
+func serviceRequest(req *http.Request, v interface{}) error {
+	req.Header.Set("User-Agent", userAgent)
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	debugf("%s %s: %d in %s", req.Method, req.URL.Redacted(), resp.StatusCode, time.Since(start).Round(time.Millisecond))
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxServiceResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: received status code %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+// sourceElement is the RSS <source> element naming the feed an item was
+// read from.
+func sourceElement(feedURL, title string) RawElement {
+	return RawElement{
+		Name:  xml.Name{Local: "source"},
+		Attrs: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: feedURL}},
+		Inner: escapeXML(title),
+	}
+}
+
+// newestIDs sorts numeric ids from newest (highest) to oldest and keeps at
+// most limit of them.
+func newestIDs(ids []int64, limit int) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// feverService talks to the Fever API, which FreshRSS, Miniflux, Tiny Tiny
+// RSS and others implement.
+type feverService struct {
+	endpoint string
+	apiKey   string
+}
+
+type feverResponse struct {
+	Auth          int    `json:"auth"`
+	UnreadItemIDs string `json:"unread_item_ids"`
+	Items         []struct {
+		ID            int64  `json:"id"`
+		FeedID        int64  `json:"feed_id"`
+		Title         string `json:"title"`
+		Author        string `json:"author"`
+		HTML          string `json:"html"`
+		URL           string `json:"url"`
+		CreatedOnTime int64  `json:"created_on_time"`
+	} `json:"items"`
+	Feeds []struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"feeds"`
+}
+
+func (f *feverService) call(query string) (feverResponse, error) {
+	var response feverResponse
+	form := url.Values{"api_key": {f.apiKey}}
+	endpoint := f.endpoint
+	if strings.Contains(endpoint, "?") {
+		endpoint += "&api&" + query
+	} else {
+		endpoint += "?api&" + query
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return response, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := serviceRequest(req, &response); err != nil {
+		return response, err
+	}
+	if response.Auth != 1 {
+		return response, errors.New("fever API rejected the credentials")
+	}
+	return response, nil
+}
+
+func (f *feverService) unread(limit int) ([]Item, []string, error) {
+	response, err := f.call("unread_item_ids")
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []int64
+	for _, field := range strings.Split(response.UnreadItemIDs, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	ids = newestIDs(ids, limit)
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	feeds, err := f.call("feeds")
+	if err != nil {
+		return nil, nil, err
+	}
+	feedsByID := make(map[int64]int)
+	for i, feed := range feeds.Feeds {
+		feedsByID[feed.ID] = i
+	}
+
+	var items []Item
+	var itemIDs []string
+	// The API returns at most 50 items per request.
+	for batchStart := 0; batchStart < len(ids); batchStart += 50 {
+		batch := ids[batchStart:min(batchStart+50, len(ids))]
+		withIDs := make([]string, len(batch))
+		for i, id := range batch {
+			withIDs[i] = strconv.FormatInt(id, 10)
+		}
+		response, err := f.call("items&with_ids=" + strings.Join(withIDs, ","))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range response.Items {
+			item := Item{
+				Title:   entry.Title,
+				Link:    entry.URL,
+				Content: entry.HTML,
+			}
+			if entry.Author != "" {
+				item.Creators = []string{entry.Author}
+			}
+			if entry.CreatedOnTime > 0 {
+				item.PubDate = time.Unix(entry.CreatedOnTime, 0).UTC().Format(time.RFC1123Z)
+			}
+			if i, ok := feedsByID[entry.FeedID]; ok {
+				item.Extra = append(item.Extra, sourceElement(feeds.Feeds[i].URL, feeds.Feeds[i].Title))
+			}
+			items = append(items, item)
+			itemIDs = append(itemIDs, strconv.FormatInt(entry.ID, 10))
+		}
+	}
+	return items, itemIDs, nil
+}
+
+func (f *feverService) markRead(ids []string) error {
+	for _, id := range ids {
+		if _, err := f.call("mark=item&as=read&id=" + url.QueryEscape(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feedbinService talks to the Feedbin REST API (v2).
+type feedbinService struct {
+	endpoint string
+	username string
+	password string
+}
+
+func (f *feedbinService) request(method, path string, body interface{}, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, f.endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(f.username, f.password)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	return serviceRequest(req, v)
+}
+
+func (f *feedbinService) unread(limit int) ([]Item, []string, error) {
+	var ids []int64
+	if err := f.request(http.MethodGet, "unread_entries.json", nil, &ids); err != nil {
+		return nil, nil, err
+	}
+	ids = newestIDs(ids, limit)
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	var subscriptions []struct {
+		FeedID  int64  `json:"feed_id"`
+		Title   string `json:"title"`
+		FeedURL string `json:"feed_url"`
+	}
+	if err := f.request(http.MethodGet, "subscriptions.json", nil, &subscriptions); err != nil {
+		return nil, nil, err
+	}
+	feedsByID := make(map[int64]int)
+	for i, subscription := range subscriptions {
+		feedsByID[subscription.FeedID] = i
+	}
+
+	var items []Item
+	var itemIDs []string
+	// The API returns at most 100 entries per request.
+	for batchStart := 0; batchStart < len(ids); batchStart += 100 {
+		batch := ids[batchStart:min(batchStart+100, len(ids))]
+		withIDs := make([]string, len(batch))
+		for i, id := range batch {
+			withIDs[i] = strconv.FormatInt(id, 10)
+		}
+		var entries []struct {
+			ID        int64   `json:"id"`
+			FeedID    int64   `json:"feed_id"`
+			Title     *string `json:"title"`
+			URL       string  `json:"url"`
+			Author    *string `json:"author"`
+			Content   *string `json:"content"`
+			Summary   *string `json:"summary"`
+			Published string  `json:"published"`
+		}
+		if err := f.request(http.MethodGet, "entries.json?ids="+strings.Join(withIDs, ","), nil, &entries); err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			item := Item{Link: entry.URL}
+			if entry.Title != nil {
+				item.Title = *entry.Title
+			}
+			if entry.Author != nil && *entry.Author != "" {
+				item.Creators = []string{*entry.Author}
+			}
+			if entry.Content != nil {
+				item.Content = *entry.Content
+			}
+			if entry.Summary != nil {
+				item.Description = *entry.Summary
+			}
+			if published, err := time.Parse(time.RFC3339, entry.Published); err == nil {
+				item.PubDate = published.Format(time.RFC1123Z)
+			}
+			if i, ok := feedsByID[entry.FeedID]; ok {
+				item.Extra = append(item.Extra, sourceElement(subscriptions[i].FeedURL, subscriptions[i].Title))
+			}
+			items = append(items, item)
+			itemIDs = append(itemIDs, strconv.FormatInt(entry.ID, 10))
+		}
+	}
+	return items, itemIDs, nil
+}
+
+func (f *feedbinService) markRead(ids []string) error {
+	// Feedbin accepts up to 1,000 ids per request.
+	for batchStart := 0; batchStart < len(ids); batchStart += 1000 {
+		var batch []int64
+		for _, id := range ids[batchStart:min(batchStart+1000, len(ids))] {
+			if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+				batch = append(batch, n)
+			}
+		}
+		body := map[string][]int64{"unread_entries": batch}
+		if err := f.request(http.MethodDelete, "unread_entries.json", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
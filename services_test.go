@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewestIDs(t *testing.T) {
+	tests := []struct {
+		ids   []int64
+		limit int
+		want  []int64
+	}{
+		{[]int64{3, 10, 7}, 0, []int64{10, 7, 3}},
+		{[]int64{3, 10, 7}, 2, []int64{10, 7}},
+		{[]int64{3}, 5, []int64{3}},
+		{nil, 5, nil},
+	}
+	for _, tt := range tests {
+		if got := newestIDs(append([]int64(nil), tt.ids...), tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newestIDs(%v, %d) = %v, want %v", tt.ids, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestNewFeedService(t *testing.T) {
+	for _, name := range []string{"FEVER_API_KEY", "FEVER_USERNAME", "FEVER_PASSWORD", "FEEDBIN_USERNAME", "FEEDBIN_PASSWORD"} {
+		t.Setenv(name, "")
+	}
+	if _, err := newFeedService("fever", ""); err == nil {
+		t.Error("fever without an endpoint succeeded")
+	}
+	if _, err := newFeedService("fever", "https://rss.example.com/api/fever.php"); err == nil {
+		t.Error("fever without credentials succeeded")
+	}
+	if _, err := newFeedService("feedbin", ""); err == nil {
+		t.Error("feedbin without credentials succeeded")
+	}
+	if _, err := newFeedService("inoreader", ""); err == nil {
+		t.Error("unknown source API succeeded")
+	}
+
+	// The Fever API key is md5("username:password").
+	t.Setenv("FEVER_USERNAME", "reader")
+	t.Setenv("FEVER_PASSWORD", "secret")
+	service, err := newFeedService("fever", "https://rss.example.com/api/fever.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := service.(*feverService).apiKey; got != "d86e2552797d076a6178f7b038ad6b69" {
+		t.Errorf("fever API key = %q, want d86e2552797d076a6178f7b038ad6b69", got)
+	}
+
+	t.Setenv("FEEDBIN_USERNAME", "reader")
+	t.Setenv("FEEDBIN_PASSWORD", "secret")
+	service, err = newFeedService("feedbin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := service.(*feedbinService).endpoint; got != defaultFeedbinURL {
+		t.Errorf("feedbin endpoint = %q, want %q", got, defaultFeedbinURL)
+	}
+}
+
+func TestFeverService(t *testing.T) {
+	var marked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("api_key") != "key" {
+			fmt.Fprint(w, `{"auth": 0}`)
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case query.Has("unread_item_ids"):
+			fmt.Fprint(w, `{"auth": 1, "unread_item_ids": "4,12,7"}`)
+		case query.Has("feeds"):
+			fmt.Fprint(w, `{"auth": 1, "feeds": [{"id": 1, "title": "Data blog", "url": "https://example.com/feed"}]}`)
+		case query.Get("with_ids") == "12,7":
+			fmt.Fprint(w, `{"auth": 1, "items": [
+				{"id": 12, "feed_id": 1, "title": "Newest", "author": "Jane Doe", "html": "<p>Hi</p>", "url": "https://example.com/12", "created_on_time": 1791800000},
+				{"id": 7, "feed_id": 2, "title": "Older", "url": "https://example.com/7"}]}`)
+		case query.Get("mark") == "item":
+			marked = append(marked, query.Get("id"))
+			fmt.Fprint(w, `{"auth": 1}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	service := &feverService{endpoint: server.URL + "/fever.php", apiKey: "key"}
+	items, ids, err := service.unread(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"12", "7"}) {
+		t.Errorf("ids = %v, want [12 7]", ids)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	newest := items[0]
+	if newest.Title != "Newest" || newest.Content != "<p>Hi</p>" || !reflect.DeepEqual(newest.Creators, []string{"Jane Doe"}) {
+		t.Errorf("item = %+v", newest)
+	}
+	if newest.PubDate == "" || len(newest.Extra) != 1 || !strings.Contains(newest.Extra[0].Inner, "Data blog") {
+		t.Errorf("item date %q and source %+v", newest.PubDate, newest.Extra)
+	}
+	if len(items[1].Extra) != 0 || items[1].PubDate != "" {
+		t.Errorf("item of an unknown feed = %+v", items[1])
+	}
+
+	if err := service.markRead(ids); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(marked, []string{"12", "7"}) {
+		t.Errorf("marked %v as read, want [12 7]", marked)
+	}
+
+	rejected := &feverService{endpoint: server.URL + "/fever.php", apiKey: "wrong"}
+	if _, _, err := rejected.unread(0); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("unread with a wrong key = %v, want a credentials error", err)
+	}
+}
+
+func TestFeedbinService(t *testing.T) {
+	var markedRead []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "reader" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v2/unread_entries.json":
+			fmt.Fprint(w, `[5, 9]`)
+		case "GET /v2/subscriptions.json":
+			fmt.Fprint(w, `[{"feed_id": 1, "title": "Data blog", "feed_url": "https://example.com/feed"}]`)
+		case "GET /v2/entries.json":
+			if got := r.URL.Query().Get("ids"); got != "9,5" {
+				t.Errorf("entries requested for ids %q, want 9,5", got)
+			}
+			fmt.Fprint(w, `[
+				{"id": 9, "feed_id": 1, "title": "Newest", "url": "https://example.com/9", "author": "Jane Doe", "content": "<p>Hi</p>", "summary": "Hi", "published": "2026-10-14T10:00:00.000000Z"},
+				{"id": 5, "feed_id": 1, "title": null, "url": "https://example.com/5", "author": null, "content": null, "summary": null, "published": "2026-10-12T10:00:00Z"}]`)
+		case "DELETE /v2/unread_entries.json":
+			var body struct {
+				UnreadEntries []int64 `json:"unread_entries"`
+			}
+			data, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(data, &body); err != nil {
+				t.Error(err)
+			}
+			markedRead = append(markedRead, body.UnreadEntries...)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	service := &feedbinService{endpoint: server.URL + "/v2/", username: "reader", password: "secret"}
+	items, ids, err := service.unread(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"9", "5"}) {
+		t.Errorf("ids = %v, want [9 5]", ids)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	want := Item{
+		Title:       "Newest",
+		Link:        "https://example.com/9",
+		Creators:    []string{"Jane Doe"},
+		Content:     "<p>Hi</p>",
+		Description: "Hi",
+		PubDate:     "Wed, 14 Oct 2026 10:00:00 +0000",
+		Extra:       []RawElement{sourceElement("https://example.com/feed", "Data blog")},
+	}
+	if !reflect.DeepEqual(items[0], want) {
+		t.Errorf("item = %+v, want %+v", items[0], want)
+	}
+	if items[1].Title != "" || items[1].Creators != nil || items[1].PubDate != "Mon, 12 Oct 2026 10:00:00 +0000" {
+		t.Errorf("item with null fields = %+v", items[1])
+	}
+
+	if err := service.markRead(append(ids, "not-a-number")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(markedRead, []int64{9, 5}) {
+		t.Errorf("marked %v as read, want [9 5]", markedRead)
+	}
+
+	unauthorized := &feedbinService{endpoint: server.URL + "/v2/", username: "reader", password: "wrong"}
+	if _, _, err := unauthorized.unread(0); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("unread with a wrong password = %v, want a 401 error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSitemaps caps how many sitemaps of a sitemap index are read.
+	maxSitemaps = 50
+
+	// defaultSitemapItems is how many pages are fetched for titles when
+	// --max-source-items doesn't say otherwise; sitemaps list up to 50,000.
+	defaultSitemapItems = 100
+)
+
+// sitemapDocument is either a <urlset> or a <sitemapindex>.
+type sitemapDocument struct {
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// loadSitemap reads the sitemap (or sitemap index) at source and turns its
+// pages into items, with lastmod as their date. Pages and child sitemaps last
+// modified before cutoff are skipped. The newest limit pages are returned,
+// newest first, followed by undated ones. The items only have a link and a
+// date, so callers fetch the pages for the rest (see enrichOpenGraph).
+func loadSitemap(source string, cutoff time.Time, limit int, maxBytes int64) ([]Item, error) {
+	var entries []sitemapEntry
+	queue := []string{source}
+	visited := make(map[string]bool)
+	for len(queue) > 0 && len(visited) < maxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if visited[sitemapURL] {
+			continue
+		}
+		visited[sitemapURL] = true
+
+		doc, err := fetchSitemap(sitemapURL, maxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", sitemapURL, err)
+		}
+		for _, child := range doc.Sitemaps {
+			if modifiedBefore(child, cutoff) {
+				continue
+			}
+			if loc := resolveURL(sitemapURL, child.Loc); loc != "" {
+				queue = append(queue, loc)
+			}
+		}
+		for _, entry := range doc.URLs {
+			if strings.TrimSpace(entry.Loc) != "" && !modifiedBefore(entry, cutoff) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if len(queue) > 0 {
+		warnf("Read only the first %d sitemaps of %s", maxSitemaps, source)
+	}
+
+	items := make([]Item, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		link := strings.TrimSpace(entry.Loc)
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		item := Item{Link: link, GUID: GUID{Value: link}}
+		if t, err := parseRSSDate(entry.LastMod); err == nil {
+			item.PubDate = t.Format(time.RFC1123Z)
+		}
+		items = append(items, item)
+	}
+	sortItemsByDate(items, false)
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+func modifiedBefore(entry sitemapEntry, cutoff time.Time) bool {
+	if cutoff.IsZero() {
+		return false
+	}
+	t, err := parseRSSDate(entry.LastMod)
+	return err == nil && t.Before(cutoff)
+}
+
+// fetchSitemap reads a sitemap, gunzipping it when it's compressed, as
+// sitemap.xml.gz files are.
+func fetchSitemap(source string, maxBytes int64) (sitemapDocument, error) {
+	var doc sitemapDocument
+	r, err := openFeed(source)
+	if err != nil {
+		return doc, err
+	}
+	defer r.Close()
+
+	var body io.Reader = bufio.NewReader(r)
+	if magic, _ := body.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return doc, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	if maxBytes > 0 {
+		body = io.LimitReader(body, maxBytes)
+	}
+	decoder := xml.NewDecoder(body)
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&doc); err != nil {
+		return doc, err
+	}
+	return doc, nil
+}
+
+// titleSitemapItems gives items whose page had no title their link as the
+// title, so they still render.
+func titleSitemapItems(items []Item) {
+	for i := range items {
+		if strings.TrimSpace(items[i].Title) == "" {
+			items[i].Title = items[i].Link
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadSitemap(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/blog/old</loc><lastmod>2025-01-01</lastmod></url>
+  <url><loc>https://example.com/blog/newest</loc><lastmod>2026-10-14</lastmod></url>
+</urlset>`))
+	gz.Close()
+
+	pages := map[string]string{
+		"/sitemap.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/pages.xml</loc><lastmod>2026-10-14</lastmod></sitemap>
+  <sitemap><loc>/blog.xml.gz</loc></sitemap>
+  <sitemap><loc>/archive.xml</loc><lastmod>2024-01-01</lastmod></sitemap>
+  <sitemap><loc>/sitemap.xml</loc></sitemap>
+</sitemapindex>`,
+		"/pages.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.com/about </loc></url>
+  <url><loc>https://example.com/blog/middle</loc><lastmod>2026-10-12T10:00:00Z</lastmod></url>
+  <url><loc>https://example.com/blog/newest</loc><lastmod>2026-10-14</lastmod></url>
+  <url><loc></loc></url>
+</urlset>`,
+		"/blog.xml.gz": gzipped.String(),
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	items, err := loadSitemap(server.URL+"/sitemap.xml", cutoff, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com/blog/newest", "https://example.com/blog/middle", "https://example.com/about"}
+	if got := itemGUIDs(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+	if items[0].PubDate != "Wed, 14 Oct 2026 00:00:00 +0000" || items[2].PubDate != "" {
+		t.Errorf("dates = %q, %q", items[0].PubDate, items[2].PubDate)
+	}
+	if wantRequests := []string{"/sitemap.xml", "/pages.xml", "/blog.xml.gz"}; !reflect.DeepEqual(requested, wantRequests) {
+		t.Errorf("requested %v, want %v", requested, wantRequests)
+	}
+
+	limited, err := loadSitemap(server.URL+"/sitemap.xml", cutoff, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemGUIDs(limited); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("items with limit 1 = %v, want %v", got, want[:1])
+	}
+
+	if _, err := loadSitemap(server.URL+"/missing.xml", time.Time{}, 0, 0); err == nil {
+		t.Error("loadSitemap of a missing sitemap succeeded")
+	}
+}
+
+func TestTitleSitemapItems(t *testing.T) {
+	items := []Item{{Title: "Kept", Link: "https://example.com/1"}, {Title: " ", Link: "https://example.com/2"}}
+	titleSitemapItems(items)
+	if items[0].Title != "Kept" || items[1].Title != "https://example.com/2" {
+		t.Errorf("titles = %q, %q", items[0].Title, items[1].Title)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS items (
+	id            TEXT PRIMARY KEY,
+	guid          TEXT,
+	link          TEXT,
+	title         TEXT,
+	published_at  TEXT,
+	pub_date      TEXT,
+	description   TEXT,
+	content       TEXT,
+	feed          TEXT,
+	raw_xml       BLOB,
+	first_seen_at TEXT NOT NULL,
+	last_seen_at  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS item_authors (
+	item_id  TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	author   TEXT NOT NULL,
+	PRIMARY KEY (item_id, position)
+);
+CREATE TABLE IF NOT EXISTS item_categories (
+	item_id  TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	category TEXT NOT NULL,
+	PRIMARY KEY (item_id, position)
+);
+CREATE INDEX IF NOT EXISTS items_published_at ON items(published_at);
+CREATE INDEX IF NOT EXISTS item_authors_author ON item_authors(author);
+CREATE INDEX IF NOT EXISTS item_categories_category ON item_categories(category);
+`
+
+// exportSQLite upserts items into the SQLite database at path, creating it
+// if needed, so every run adds to an archive of everything ever emitted.
+// Items are keyed like in merging, on their normalized GUID or link; a
+// re-emitted item is updated in place and keeps its first_seen_at.
+func exportSQLite(path, feed string, items []Item, now time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seen := now.UTC().Format(time.RFC3339)
+	for _, item := range cleanItems(items) {
+		id := itemKey(item)
+		if id == "" {
+			continue
+		}
+		var publishedAt interface{}
+		if t, err := parseRSSDate(item.PubDate); err == nil {
+			publishedAt = t.UTC().Format(time.RFC3339)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO items (id, guid, link, title, published_at, pub_date, description, content, feed, raw_xml, first_seen_at, last_seen_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				guid = excluded.guid,
+				link = excluded.link,
+				title = excluded.title,
+				published_at = excluded.published_at,
+				pub_date = excluded.pub_date,
+				description = excluded.description,
+				content = excluded.content,
+				feed = excluded.feed,
+				raw_xml = excluded.raw_xml,
+				last_seen_at = excluded.last_seen_at`,
+			id, item.GUID.Value, item.Link, item.Title, publishedAt, item.PubDate,
+			item.Description, item.Content, feed, []byte(renderItemXML(item)), seen, seen)
+		if err != nil {
+			return fmt.Errorf("failed to upsert %s: %w", id, err)
+		}
+
+		if err := replaceItemValues(tx, "item_authors", "author", id, item.authors()); err != nil {
+			return err
+		}
+		if err := replaceItemValues(tx, "item_categories", "category", id, item.Categories); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func replaceItemValues(tx *sql.Tx, table, column, id string, values []string) error {
+	if _, err := tx.Exec("DELETE FROM "+table+" WHERE item_id = ?", id); err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	position := 0
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		query := fmt.Sprintf("INSERT INTO %s (item_id, position, %s) VALUES (?, ?, ?)", table, column)
+		if _, err := tx.Exec(query, id, position, value); err != nil {
+			return fmt.Errorf("failed to update %s: %w", table, err)
+		}
+		position++
+	}
+	return nil
+}
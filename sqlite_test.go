@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExportSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.db")
+	first := time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	item := Item{
+		Title:      "Kubernetes tips",
+		Link:       "https://example.com/1",
+		GUID:       GUID{Value: "https://example.com/1"},
+		PubDate:    "Wed, 14 Oct 2026 12:00:00 +0200",
+		Creators:   []string{"Jane Doe", " ", "John Smith"},
+		Categories: []string{"k8s", "ops"},
+	}
+	if err := exportSQLite(path, "Data blog", []Item{item, {Title: "No link"}}, first); err != nil {
+		t.Fatal(err)
+	}
+	item.Title = "Kubernetes tips, updated"
+	item.Creators = []string{"Jane Doe"}
+	if err := exportSQLite(path, "Data blog", []Item{item}, second); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("items table has %d rows, want 1", count)
+	}
+
+	var title, publishedAt, feed, firstSeen, lastSeen string
+	err = db.QueryRow("SELECT title, published_at, feed, first_seen_at, last_seen_at FROM items WHERE id = ?", itemKey(item)).
+		Scan(&title, &publishedAt, &feed, &firstSeen, &lastSeen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Kubernetes tips, updated" || publishedAt != "2026-10-14T10:00:00Z" || feed != "Data blog" {
+		t.Errorf("row = %q, %q, %q", title, publishedAt, feed)
+	}
+	if firstSeen != "2026-10-15T06:00:00Z" || lastSeen != "2026-10-16T06:00:00Z" {
+		t.Errorf("first and last seen = %s, %s; want the first and second run", firstSeen, lastSeen)
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT author FROM item_authors ORDER BY position", []string{"Jane Doe"}},
+		{"SELECT category FROM item_categories ORDER BY position", []string{"k8s", "ops"}},
+	} {
+		rows, err := db.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, value)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runState is persisted between runs in the --state-file, so scheduled
+// pipelines can pick up where the previous successful run left off.
+type runState struct {
+	LastSuccessfulRun time.Time `json:"last_successful_run"`
+	// ContentHashes maps the key of every item emitted by the last
+	// successful run to its content hash, to spot edits in the next run.
+	ContentHashes map[string]string `json:"content_hashes,omitempty"`
+	// Notified maps each notifier to the keys of the items it announced,
+	// with the time they were announced.
+	Notified map[string]map[string]time.Time `json:"notified,omitempty"`
+}
+
+func loadState(path string) (runState, error) {
+	var state runState
+	if path == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveState(path string, state runState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a half-written file.
+func writeFileAtomic(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseSince turns a --since value into a cutoff time. Plain numbers are
+// days, for compatibility with earlier versions; "Nd" is also days; anything
+// else is a Go duration such as "36h" or "90m". "last-run" uses the time of
+// the last successful run from the state file. A zero time means no cutoff.
+func parseSince(value string, now time.Time, state runState) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "" || value == "0":
+		return time.Time{}, nil
+	case value == "last-run":
+		return state.LastSuccessfulRun, nil
+	}
+
+	days := strings.TrimSuffix(value, "d")
+	if n, err := strconv.Atoi(days); err == nil {
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("negative number of days %d", n)
+		}
+		if n == 0 {
+			return time.Time{}, nil
+		}
+		return now.AddDate(0, 0, -n), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected days, a duration like 36h, or last-run: %q", value)
+	}
+	if d < 0 {
+		return time.Time{}, fmt.Errorf("negative duration %s", d)
+	}
+	return now.Add(-d), nil
+}
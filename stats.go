@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	feedURL := fs.String("feed", "", "RSS feed URL or file to analyze")
+	var filterOpts filterFlags
+	filterOpts.register(fs)
+	fs.Parse(args)
+
+	if *feedURL == "" {
+		fmt.Fprintf(os.Stderr, "Error: --feed is required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if filterOpts.since == "last-run" {
+		fatalf("Error: --since last-run is not supported by stats")
+	}
+	cutoff, err := parseSince(filterOpts.since, time.Now(), runState{})
+	if err != nil {
+		fatalf("Error: --since: %v", err)
+	}
+	filters, err := filterOpts.buildFilters(cutoff)
+	if err != nil {
+		fatalf("Error: %v", err)
+	}
+
+	items, err := loadFeedItems(*feedURL)
+	if err != nil {
+		fatalf("Error loading feed: %v", err)
+	}
+
+	byAuthor := make(map[string]int)
+	byCategory := make(map[string]int)
+	byMonth := make(map[string]int)
+	for _, item := range items {
+		authors := item.authors()
+		if len(authors) == 0 {
+			authors = []string{"Unknown"}
+		}
+		for _, author := range authors {
+			byAuthor[author]++
+		}
+		for _, category := range item.Categories {
+			byCategory[category]++
+		}
+		month := "undated"
+		if pubDate, err := parseRSSDate(item.PubDate); err == nil {
+			month = pubDate.Format("2006-01")
+		}
+		byMonth[month]++
+	}
+
+	fmt.Printf("Items: %d\n", len(items))
+	printCounts("By author", byAuthor, false)
+	printCounts("By category", byCategory, false)
+	printCounts("By month", byMonth, true)
+
+	if len(filters) > 0 {
+		fmt.Println("\nFilters (items each filter would drop on its own):")
+		for _, filter := range filters {
+			dropped := 0
+			for _, item := range items {
+				if !filter.keep(item) {
+					dropped++
+				}
+			}
+			fmt.Printf("  %6d  %s\n", dropped, filter.name)
+		}
+		kept, _ := applyFilters(items, filters)
+		fmt.Printf("  %6d  kept by all filters\n", len(kept))
+	}
+}
+
+// printCounts prints counts sorted by frequency, or by key when byKey is set
+// (used for months, which read best chronologically).
+func printCounts(title string, counts map[string]int, byKey bool) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !byKey && counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n%s:\n", title)
+	for _, key := range keys {
+		fmt.Printf("  %6d  %s\n", counts[key], key)
+	}
+}
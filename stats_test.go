@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunStats(t *testing.T) {
+	feed := filepath.Join(t.TempDir(), "feed.xml")
+	doc := `<rss xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+<item><title>1</title><dc:creator>Alice</dc:creator><category>go</category><pubDate>Mon, 12 Oct 2026 10:00:00 +0000</pubDate>
+<enclosure url="https://example.com/1.mp3" type="audio/mpeg"/></item>
+<item><title>2</title><dc:creator>Alice</dc:creator><dc:creator>Bob</dc:creator><category>go</category><category>data</category><pubDate>Sat, 12 Sep 2026 10:00:00 +0000</pubDate></item>
+<item><title>3</title></item>
+</channel></rss>`
+	if err := os.WriteFile(feed, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := captureOutput(t, &os.Stdout, func() {
+		runStats([]string{"--feed", feed, "--require-enclosure", "audio/*"})
+	})
+	want := `Items: 3
+
+By author:
+       2  Alice
+       1  Bob
+       1  Unknown
+
+By category:
+       2  go
+       1  data
+
+By month:
+       1  2026-09
+       1  2026-10
+       1  undated
+
+Filters (items each filter would drop on its own):
+       2  require-enclosure
+       1  kept by all filters
+`
+	if got != want {
+		t.Errorf("stats output:\n%s\nwant:\n%s", got, want)
+	}
+}
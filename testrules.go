@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ruleExpectation is one line of a test-rules spec file:
+//
+//	keep <guid or link>
+//	drop <guid or link> [filter name]
+type ruleExpectation struct {
+	line   int
+	keep   bool
+	id     string
+	filter string
+}
+
+func runTestRules(args []string) {
+	fs := flag.NewFlagSet("test-rules", flag.ExitOnError)
+	fixture := fs.String("fixture", "", "Feed file (or URL) to run the rules against")
+	specFile := fs.String("spec", "", "File listing expected 'keep <guid>' and 'drop <guid> [filter]' lines")
+	now := fs.String("now", "", "Reference time for --since as RFC 3339, so date rules are reproducible (default: current time)")
+	var filterOpts filterFlags
+	filterOpts.register(fs)
+	fs.Parse(args)
+
+	if *fixture == "" || *specFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: --fixture and --spec are required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	reference := time.Now()
+	if *now != "" {
+		t, err := time.Parse(time.RFC3339, *now)
+		if err != nil {
+			fatalf("Error: --now: %v", err)
+		}
+		reference = t
+	}
+	if filterOpts.since == "last-run" {
+		fatalf("Error: --since last-run is not supported by test-rules")
+	}
+	cutoff, err := parseSince(filterOpts.since, reference, runState{})
+	if err != nil {
+		fatalf("Error: --since: %v", err)
+	}
+	filters, err := filterOpts.buildFilters(cutoff)
+	if err != nil {
+		fatalf("Error: %v", err)
+	}
+
+	expectations, err := loadRuleSpec(*specFile)
+	if err != nil {
+		fatalf("Error loading spec: %v", err)
+	}
+	items, err := loadFeedItems(*fixture)
+	if err != nil {
+		fatalf("Error loading fixture: %v", err)
+	}
+
+	failures := 0
+	for _, expectation := range expectations {
+		if msg := checkExpectation(expectation, items, filters); msg != "" {
+			failures++
+			fmt.Printf("FAIL %s:%d: %s\n", *specFile, expectation.line, msg)
+		}
+	}
+	fmt.Printf("%d expectations, %d passed, %d failed\n", len(expectations), len(expectations)-failures, failures)
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadRuleSpec(path string) ([]ruleExpectation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var expectations []ruleExpectation
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 || fields[0] != "keep" && fields[0] != "drop" {
+			return nil, fmt.Errorf("line %d: expected 'keep <id>' or 'drop <id> [filter]'", lineNumber)
+		}
+		expectation := ruleExpectation{line: lineNumber, keep: fields[0] == "keep", id: fields[1]}
+		if len(fields) == 3 {
+			if expectation.keep {
+				return nil, fmt.Errorf("line %d: only drop lines can name a filter", lineNumber)
+			}
+			expectation.filter = fields[2]
+		}
+		expectations = append(expectations, expectation)
+	}
+	return expectations, scanner.Err()
+}
+
+func checkExpectation(expectation ruleExpectation, items []Item, filters []itemFilter) string {
+	for _, item := range items {
+		if item.GUID.Value != expectation.id && item.Link != expectation.id {
+			continue
+		}
+		rejectedBy := firstRejectingFilter(item, filters)
+		switch {
+		case expectation.keep && rejectedBy != "":
+			return fmt.Sprintf("expected %s to be kept, but %s dropped it", expectation.id, rejectedBy)
+		case !expectation.keep && rejectedBy == "":
+			return fmt.Sprintf("expected %s to be dropped, but it was kept", expectation.id)
+		case expectation.filter != "" && rejectedBy != expectation.filter:
+			return fmt.Sprintf("expected %s to be dropped by %s, but %s dropped it", expectation.id, expectation.filter, rejectedBy)
+		}
+		return ""
+	}
+	return fmt.Sprintf("%s is not in the fixture", expectation.id)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRuleSpec(t *testing.T) {
+	path := writeTestFile(t, "spec.txt", "# expectations\nkeep id-1\n\ndrop https://example.com/2 since\ndrop id-3\n")
+	got, err := loadRuleSpec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ruleExpectation{
+		{line: 2, keep: true, id: "id-1"},
+		{line: 4, id: "https://example.com/2", filter: "since"},
+		{line: 5, id: "id-3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadRuleSpec = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"keep", "maybe id-1", "keep id-1 authors", "drop a b c"} {
+		if _, err := loadRuleSpec(writeTestFile(t, "bad.txt", bad)); err == nil {
+			t.Errorf("loadRuleSpec accepted %q", bad)
+		}
+	}
+}
+
+func TestCheckExpectation(t *testing.T) {
+	items := []Item{
+		{GUID: GUID{Value: "go"}, Categories: []string{"go"}},
+		{Link: "https://example.com/rust", Categories: []string{"rust"}},
+	}
+	filters := []itemFilter{{
+		name: "no-rust",
+		keep: func(item Item) bool { return len(item.Categories) == 0 || item.Categories[0] != "rust" },
+	}}
+	tests := []struct {
+		expectation ruleExpectation
+		wantPass    bool
+	}{
+		{ruleExpectation{keep: true, id: "go"}, true},
+		{ruleExpectation{id: "go"}, false},
+		{ruleExpectation{id: "https://example.com/rust"}, true},
+		{ruleExpectation{id: "https://example.com/rust", filter: "no-rust"}, true},
+		{ruleExpectation{id: "https://example.com/rust", filter: "since"}, false},
+		{ruleExpectation{keep: true, id: "https://example.com/rust"}, false},
+		{ruleExpectation{keep: true, id: "missing"}, false},
+	}
+	for _, tt := range tests {
+		msg := checkExpectation(tt.expectation, items, filters)
+		if (msg == "") != tt.wantPass {
+			t.Errorf("checkExpectation(%+v) = %q, want pass: %v", tt.expectation, msg, tt.wantPass)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type severity int
+
+const (
+	severityInfo severity = iota
+	severityWarning
+	severityError
+)
+
+func (s severity) String() string {
+	switch s {
+	case severityError:
+		return "ERROR"
+	case severityWarning:
+		return "WARNING"
+	}
+	return "INFO"
+}
+
+type finding struct {
+	severity severity
+	item     int // 1-based item position, 0 for feed-level findings
+	message  string
+}
+
+var (
+	htmlMarkupPattern = regexp.MustCompile(`<(/?)[a-zA-Z][a-zA-Z0-9]*(\s[^>]*)?/?>`)
+	htmlElementNames  = map[string]bool{
+		"a": true, "b": true, "br": true, "div": true, "em": true, "h1": true, "h2": true, "h3": true,
+		"i": true, "img": true, "li": true, "p": true, "span": true, "strong": true, "ul": true,
+	}
+)
+
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	failOn := fs.String("fail-on", "", "Exit with status 1 when a finding of this severity or worse exists: 'warning' or 'error'")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s validate [flags] <feed file or URL>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	threshold := severityError + 1
+	switch *failOn {
+	case "":
+	case "warning":
+		threshold = severityWarning
+	case "error":
+		threshold = severityError
+	default:
+		fatalf("Error: --fail-on must be 'warning' or 'error'")
+	}
+
+	source, err := openFeed(fs.Arg(0))
+	if err != nil {
+		fatalf("Error opening feed: %v", err)
+	}
+	channel, err := decodeFeed(source, decodeOptions{maxBytes: defaultMaxFeedBytes})
+	source.Close()
+	if err != nil {
+		fatalf("Error parsing RSS: %v", err)
+	}
+
+	items := channel.Items
+	findings := append(validateChannel(channel), validateItems(items)...)
+	counts := make(map[severity]int)
+	worst := severityInfo
+	for _, f := range findings {
+		counts[f.severity]++
+		if f.severity > worst {
+			worst = f.severity
+		}
+		if f.item == 0 {
+			fmt.Printf("%s: %s\n", f.severity, f.message)
+		} else {
+			fmt.Printf("%s: item %d (%s): %s\n", f.severity, f.item, itemLabel(items[f.item-1]), f.message)
+		}
+	}
+	fmt.Printf("%d items checked: %d errors, %d warnings\n", len(items), counts[severityError], counts[severityWarning])
+
+	if len(findings) > 0 && worst >= threshold {
+		os.Exit(1)
+	}
+}
+
+func validateChannel(channel Channel) []finding {
+	var findings []finding
+	for _, field := range []struct{ name, value string }{
+		{"title", channel.Title},
+		{"link", channel.Link},
+		{"description", channel.Description},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			findings = append(findings, finding{severityError, 0, fmt.Sprintf("channel is missing required <%s>", field.name)})
+		}
+	}
+	if channel.selfLink() == "" {
+		findings = append(findings, finding{severityWarning, 0, "channel has no atom:link rel=\"self\""})
+	}
+	if channel.Image != nil && channel.Image.URL == "" {
+		findings = append(findings, finding{severityError, 0, "channel <image> is missing <url>"})
+	}
+	return findings
+}
+
+func validateItems(items []Item) []finding {
+	var findings []finding
+	if len(items) == 0 {
+		findings = append(findings, finding{severityWarning, 0, "feed contains no items"})
+	}
+
+	seenGUIDs := make(map[string]int)
+	seenLinks := make(map[string]int)
+	for i, item := range items {
+		n := i + 1
+		add := func(s severity, format string, args ...interface{}) {
+			findings = append(findings, finding{s, n, fmt.Sprintf(format, args...)})
+		}
+
+		if strings.TrimSpace(item.Title) == "" && strings.TrimSpace(item.Description) == "" {
+			add(severityError, "item has neither a title nor a description")
+		}
+		if strings.TrimSpace(item.Link) == "" {
+			add(severityWarning, "missing link")
+		}
+
+		if item.GUID.Value == "" {
+			add(severityWarning, "missing guid; readers fall back to guessing identity")
+		} else if first, ok := seenGUIDs[item.GUID.Value]; ok {
+			add(severityError, "duplicate guid %q (first used by item %d)", item.GUID.Value, first)
+		} else {
+			seenGUIDs[item.GUID.Value] = n
+		}
+		if item.Link != "" {
+			if first, ok := seenLinks[item.Link]; ok {
+				add(severityWarning, "duplicate link %q (first used by item %d)", item.Link, first)
+			} else {
+				seenLinks[item.Link] = n
+			}
+		}
+
+		if item.PubDate == "" {
+			add(severityWarning, "missing pubDate")
+		} else if !isRFC822Date(item.PubDate) {
+			if _, err := parseRSSDate(item.PubDate); err == nil {
+				add(severityWarning, "pubDate %q is not an RFC 822 date", item.PubDate)
+			} else {
+				add(severityError, "pubDate %q cannot be parsed", item.PubDate)
+			}
+		}
+
+		if htmlMarkupPattern.MatchString(item.Title) {
+			add(severityWarning, "title contains HTML markup, which most readers show literally")
+		}
+		for _, extra := range item.Extra {
+			if extra.Name.Space == "" && htmlElementNames[strings.ToLower(extra.Name.Local)] {
+				add(severityError, "unescaped HTML element <%s> directly inside item", extra.Name.Local)
+			}
+		}
+	}
+	return findings
+}
+
+func isRFC822Date(dateStr string) bool {
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822} {
+		if _, err := time.Parse(layout, strings.TrimSpace(dateStr)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func itemLabel(item Item) string {
+	if item.Title != "" {
+		return item.Title
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return "untitled"
+}
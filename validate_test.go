@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestValidateChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		want    []severity
+	}{
+		{
+			name: "complete",
+			channel: Channel{Title: "Blog", Link: "https://example.com/", Description: "Posts",
+				AtomLinks: []AtomLink{{Rel: "self", Href: "https://example.com/feed"}}},
+		},
+		{
+			name:    "missing everything",
+			channel: Channel{Image: &Image{}},
+			want:    []severity{severityError, severityError, severityError, severityWarning, severityError},
+		},
+	}
+	for _, tt := range tests {
+		var got []severity
+		for _, f := range validateChannel(tt.channel) {
+			got = append(got, f.severity)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: severities = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateItems(t *testing.T) {
+	valid := Item{Title: "Post", Link: "https://example.com/1", GUID: GUID{Value: "1"}, PubDate: "Mon, 12 Oct 2026 10:00:00 +0000"}
+	with := func(change func(*Item)) Item {
+		item := valid
+		change(&item)
+		return item
+	}
+	tests := []struct {
+		name  string
+		items []Item
+		want  []finding
+	}{
+		{"valid", []Item{valid}, nil},
+		{"no items", nil, []finding{{severityWarning, 0, "feed contains no items"}}},
+		{"no title or description", []Item{with(func(i *Item) { i.Title = " " })},
+			[]finding{{severityError, 1, "item has neither a title nor a description"}}},
+		{"no link, guid or date", []Item{{Title: "Post"}}, []finding{
+			{severityWarning, 1, "missing link"},
+			{severityWarning, 1, "missing guid; readers fall back to guessing identity"},
+			{severityWarning, 1, "missing pubDate"},
+		}},
+		{"duplicates", []Item{valid, valid}, []finding{
+			{severityError, 2, `duplicate guid "1" (first used by item 1)`},
+			{severityWarning, 2, `duplicate link "https://example.com/1" (first used by item 1)`},
+		}},
+		{"ISO date", []Item{with(func(i *Item) { i.PubDate = "2026-10-12T10:00:00Z" })},
+			[]finding{{severityWarning, 1, `pubDate "2026-10-12T10:00:00Z" is not an RFC 822 date`}}},
+		{"bad date", []Item{with(func(i *Item) { i.PubDate = "yesterday" })},
+			[]finding{{severityError, 1, `pubDate "yesterday" cannot be parsed`}}},
+		{"markup", []Item{with(func(i *Item) {
+			i.Title = "<b>Post</b>"
+			i.Extra = []RawElement{{Name: xml.Name{Local: "p"}}, {Name: xml.Name{Local: "enclosure"}}}
+		})}, []finding{
+			{severityWarning, 1, "title contains HTML markup, which most readers show literally"},
+			{severityError, 1, "unescaped HTML element <p> directly inside item"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := validateItems(tt.items); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: findings = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}